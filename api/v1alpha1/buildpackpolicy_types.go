@@ -0,0 +1,73 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BuildpackPolicySpec defines the buildpacks trusted to build the images of
+// applications
+type BuildpackPolicySpec struct {
+	// Selector limits the policy to the applications with matching labels.
+	// The policy applies to every application when empty.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Buildpacks lists the buildpacks trusted to build images. Every
+	// buildpack that built an image must match an entry, and be at least the
+	// minimum version of every entry it matches.
+	Buildpacks []TrustedBuildpack `json:"buildpacks"`
+}
+
+// TrustedBuildpack is a buildpack trusted to build images
+type TrustedBuildpack struct {
+	// ID of the buildpack, `*` matches any characters except `/`, like
+	// `paketo-buildpacks/*`
+	ID string `json:"id"`
+
+	// MinimumVersion of the buildpack, every version is trusted when empty
+	// +optional
+	MinimumVersion string `json:"minimumVersion,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BuildpackPolicy is the Schema for the buildpackpolicies API. The images of
+// SpringBootApplications must be built by buildpacks trusted by every
+// BuildpackPolicy that selects the application.
+type BuildpackPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BuildpackPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BuildpackPolicyList contains a list of BuildpackPolicy
+type BuildpackPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BuildpackPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BuildpackPolicy{}, &BuildpackPolicyList{})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Selects returns true if the policy applies to the application
+func (s *BuildpackPolicySpec) Selects(application *SpringBootApplication) (bool, error) {
+	if s.Selector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(s.Selector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(application.Labels)), nil
+}
+
+// Violations describes the buildpacks that are not trusted by the policy. An
+// image that was not built by buildpacks violates every policy.
+func (s *BuildpackPolicySpec) Violations(buildpacks []cnb.Buildpack) []string {
+	if len(buildpacks) == 0 {
+		return []string{"image was not built by buildpacks"}
+	}
+	violations := []string{}
+	for _, bp := range buildpacks {
+		if violation := s.violation(bp); violation != "" {
+			violations = append(violations, violation)
+		}
+	}
+	return violations
+}
+
+func (s *BuildpackPolicySpec) violation(bp cnb.Buildpack) string {
+	trusted := false
+	for _, t := range s.Buildpacks {
+		if matched, _ := path.Match(t.ID, bp.ID); !matched {
+			continue
+		}
+		if t.MinimumVersion != "" && cnb.CompareVersions(bp.Version, t.MinimumVersion) < 0 {
+			return fmt.Sprintf("buildpack %s %s is older than %s", bp.ID, bp.Version, t.MinimumVersion)
+		}
+		trusted = true
+	}
+	if !trusted {
+		return fmt.Sprintf("buildpack %s %s is not trusted", bp.ID, bp.Version)
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LicensePolicySpec defines the licenses the dependencies of applications may
+// be licensed under
+type LicensePolicySpec struct {
+	// Allow lists the SPDX license identifiers dependencies may be licensed
+	// under. Every license that is not denied is allowed when empty.
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny lists the SPDX license identifiers dependencies may not be
+	// licensed under
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+
+	// DenyUnknown treats dependencies without license information as
+	// violations of the policy
+	// +optional
+	DenyUnknown bool `json:"denyUnknown,omitempty"`
+
+	// Exceptions exempt dependencies from the policy
+	// +optional
+	Exceptions []LicensePolicyException `json:"exceptions,omitempty"`
+}
+
+// LicensePolicyException exempts a dependency from a license policy
+type LicensePolicyException struct {
+	// Name of the dependency
+	Name string `json:"name"`
+
+	// Version of the dependency, every version is exempt when empty
+	// +optional
+	Version string `json:"version,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// LicensePolicy is the Schema for the licensepolicies API. Every
+// SpringBootApplication must comply with every LicensePolicy in the cluster.
+type LicensePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LicensePolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LicensePolicyList contains a list of LicensePolicy
+type LicensePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LicensePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LicensePolicy{}, &LicensePolicyList{})
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+)
+
+// Violations returns the dependencies that are not licensed as the policy
+// requires. Dependencies listing several licenses comply when any of the
+// licenses is permitted.
+func (s *LicensePolicySpec) Violations(dependencies []cnb.Dependency) []cnb.Dependency {
+	policy := cnb.LicensePolicy{Allow: s.Allow, Deny: s.Deny}
+	violations := []cnb.Dependency{}
+	for _, d := range dependencies {
+		if s.exempt(d) {
+			continue
+		}
+		if len(d.Licenses) == 0 {
+			if s.DenyUnknown {
+				violations = append(violations, d)
+			}
+			continue
+		}
+		permitted := false
+		for _, license := range d.Licenses {
+			if policy.Permits(license) {
+				permitted = true
+				break
+			}
+		}
+		if !permitted {
+			violations = append(violations, d)
+		}
+	}
+	return violations
+}
+
+func (s *LicensePolicySpec) exempt(d cnb.Dependency) bool {
+	for _, e := range s.Exceptions {
+		if e.Name == d.Name && (e.Version == "" || e.Version == d.Version) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	"github.com/spring-cloud-incubator/mononoke/opinions"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Opinion converts the resource to an opinion, identified by the resource's
+// name
+func (o *Opinion) Opinion() (opinions.Opinion, error) {
+	if err := validateConditions(o.Name, o.Spec.When); err != nil {
+		return nil, err
+	}
+	spec := o.Spec.DeepCopy()
+	return &opinions.BasicOpinion{
+		Id:           o.Name,
+		DependsOn:    spec.DependsOn,
+		After:        spec.After,
+		Dependencies: conditionDependencies(spec.When),
+		ApplicableFunc: func(ctx context.Context, applied opinions.AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			return conditionsHold(ctx, spec.When, imageMetadata)
+		},
+		ApplyFunc: func(ctx context.Context, target opinions.Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			spec.Effects.apply(ctx, target, containerIdx)
+			return nil
+		},
+	}, nil
+}
+
+// validateConditions checks that each condition of the opinion sets exactly
+// one field
+func validateConditions(name string, when []OpinionCondition) error {
+	for i, condition := range when {
+		set := 0
+		if len(condition.Dependencies) != 0 {
+			set++
+		}
+		if c := condition.DependencyConstraint; c != nil {
+			set++
+			if _, err := semver.NewConstraint(c.Constraint); err != nil {
+				return fmt.Errorf("invalid dependency constraint %q for opinion %q: %w", c.Constraint, name, err)
+			}
+		}
+		if condition.Property != nil {
+			set++
+		}
+		if set != 1 {
+			return fmt.Errorf("condition %d of opinion %q must set exactly one of dependencies, dependencyConstraint or property", i, name)
+		}
+	}
+	return nil
+}
+
+// conditionDependencies lists the dependencies the conditions match
+func conditionDependencies(when []OpinionCondition) []string {
+	dependencies := []string{}
+	for _, condition := range when {
+		dependencies = append(dependencies, condition.Dependencies...)
+		if c := condition.DependencyConstraint; c != nil {
+			dependencies = append(dependencies, c.Name)
+		}
+	}
+	return dependencies
+}
+
+// conditionsHold returns true when every condition holds for the image and
+// the application properties
+func conditionsHold(ctx context.Context, when []OpinionCondition, imageMetadata cnb.ImageMetadata) bool {
+	bootMetadata := opinions.NewSpringBootBOMMetadata(imageMetadata)
+	applicationProperties := opinions.GetSpringApplicationProperties(ctx)
+	for _, condition := range when {
+		switch {
+		case len(condition.Dependencies) != 0:
+			if !bootMetadata.HasDependency(condition.Dependencies...) {
+				return false
+			}
+		case condition.DependencyConstraint != nil:
+			if !bootMetadata.HasDependencyConstraint(condition.DependencyConstraint.Name, condition.DependencyConstraint.Constraint) {
+				return false
+			}
+		case condition.Property != nil:
+			value, ok := applicationProperties[condition.Property.Key]
+			if !ok || (condition.Property.Value != "" && condition.Property.Value != value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (e *OpinionEffects) apply(ctx context.Context, target opinions.Resource, containerIdx int) {
+	for key, value := range e.Labels {
+		opinions.SetLabel(target, key, value)
+	}
+	for key, value := range e.Annotations {
+		opinions.SetAnnotation(target, key, value)
+	}
+	applicationProperties := opinions.GetSpringApplicationProperties(ctx)
+	for key, value := range e.ApplicationProperties {
+		applicationProperties.Default(key, value)
+	}
+
+	c := &target.PodTemplate().Spec.Containers[containerIdx]
+	for _, env := range e.Env {
+		defined := false
+		for _, existing := range c.Env {
+			if existing.Name == env.Name {
+				defined = true
+				break
+			}
+		}
+		if !defined {
+			c.Env = append(c.Env, *env.DeepCopy())
+		}
+	}
+	if c.LivenessProbe == nil && e.LivenessProbe != nil {
+		c.LivenessProbe = e.LivenessProbe.DeepCopy()
+	}
+	if c.ReadinessProbe == nil && e.ReadinessProbe != nil {
+		c.ReadinessProbe = e.ReadinessProbe.DeepCopy()
+	}
+	if e.Resources != nil {
+		c.Resources.Requests = defaultResources(c.Resources.Requests, e.Resources.Requests)
+		c.Resources.Limits = defaultResources(c.Resources.Limits, e.Resources.Limits)
+	}
+}
+
+func defaultResources(resources, defaults corev1.ResourceList) corev1.ResourceList {
+	for name, quantity := range defaults {
+		if _, ok := resources[name]; ok {
+			continue
+		}
+		if resources == nil {
+			resources = corev1.ResourceList{}
+		}
+		resources[name] = quantity.DeepCopy()
+	}
+	return resources
+}
+
+// InvalidOpinion is an Opinion or OpinionProvider that is not applied
+// +kubebuilder:object:generate=false
+type InvalidOpinion struct {
+	// Object is the Opinion or OpinionProvider
+	Object  runtime.Object
+	Message string
+}
+
+// unorderableOpinion is an opinion that can't be ordered among the others
+// +kubebuilder:object:generate=false
+type unorderableOpinion struct {
+	item    orderedOpinion
+	message string
+}
+
+// orderedOpinion is an Opinion or OpinionProvider with its order
+// +kubebuilder:object:generate=false
+type orderedOpinion struct {
+	order   int32
+	opinion opinions.Opinion
+	object  runtime.Object
+}
+
+// orderable splits the custom opinions into those that can be ordered among
+// the built-in opinions and those that depend on or are after an unknown
+// opinion, or whose dependencies form a cycle. Dropping an opinion may leave
+// the opinions that depend on it with an unknown dependency in turn, so
+// opinions are checked until none are dropped.
+func orderable(builtin opinions.Opinions, items []orderedOpinion) ([]orderedOpinion, []unorderableOpinion) {
+	unorderable := []unorderableOpinion{}
+	for {
+		all := append(opinions.Opinions{}, builtin...)
+		known := map[string]bool{}
+		for _, o := range builtin {
+			known[o.GetId()] = true
+		}
+		for _, item := range items {
+			all = append(all, item.opinion)
+			known[item.opinion.GetId()] = true
+		}
+		cyclic := map[string]bool{}
+		for _, id := range all.Cyclic() {
+			cyclic[id] = true
+		}
+
+		kept := []orderedOpinion{}
+		for _, item := range items {
+			o := item.opinion
+			unknown := []string{}
+			if d, ok := o.(opinions.DependentOpinion); ok {
+				for _, id := range append(append([]string{}, d.GetDependsOn()...), d.GetAfter()...) {
+					if !known[id] {
+						unknown = append(unknown, id)
+					}
+				}
+			}
+			switch {
+			case len(unknown) != 0:
+				unorderable = append(unorderable, unorderableOpinion{item: item, message: fmt.Sprintf("opinion %q depends on or is after unknown opinions %s", o.GetId(), strings.Join(unknown, ", "))})
+			case cyclic[o.GetId()]:
+				unorderable = append(unorderable, unorderableOpinion{item: item, message: fmt.Sprintf("opinion %q can't be ordered, its dependencies form a cycle", o.GetId())})
+			default:
+				kept = append(kept, item)
+			}
+		}
+		if len(kept) == len(items) {
+			return items, unorderable
+		}
+		items = kept
+	}
+}
+
+// Opinions places the Opinions and OpinionProviders among the built-in
+// opinions. Opinions with a negative order are applied before the built-in
+// opinions, others after, ordered by their order then by name. The
+// dependencies of opinions take precedence over the order when the opinions
+// are applied.
+//
+// Invalid opinions, opinions whose ID is already used by a built-in opinion
+// or by a resource of the other kind, and opinions that depend on or are
+// after an unknown opinion or whose dependencies form a cycle, are returned
+// separately so one misconfigured resource doesn't keep every other opinion
+// from applying.
+func Opinions(builtin opinions.Opinions, opinionList *OpinionList, providerList *OpinionProviderList) (opinions.Opinions, []InvalidOpinion) {
+	items := []orderedOpinion{}
+	invalid := []InvalidOpinion{}
+	for i := range opinionList.Items {
+		o, err := opinionList.Items[i].Opinion()
+		if err != nil {
+			invalid = append(invalid, InvalidOpinion{Object: &opinionList.Items[i], Message: err.Error()})
+			continue
+		}
+		items = append(items, orderedOpinion{order: opinionList.Items[i].Spec.Order, opinion: o, object: &opinionList.Items[i]})
+	}
+	for i := range providerList.Items {
+		o, err := providerList.Items[i].Opinion()
+		if err != nil {
+			invalid = append(invalid, InvalidOpinion{Object: &providerList.Items[i], Message: err.Error()})
+			continue
+		}
+		items = append(items, orderedOpinion{order: providerList.Items[i].Spec.Order, opinion: o, object: &providerList.Items[i]})
+	}
+
+	builtinIds := map[string]bool{}
+	for _, o := range builtin {
+		builtinIds[o.GetId()] = true
+	}
+	ids := map[string]int{}
+	for _, item := range items {
+		ids[item.opinion.GetId()]++
+	}
+	valid := []orderedOpinion{}
+	for _, item := range items {
+		id := item.opinion.GetId()
+		switch {
+		case builtinIds[id]:
+			invalid = append(invalid, InvalidOpinion{Object: item.object, Message: fmt.Sprintf("opinion %q has the same ID as a built-in opinion", id)})
+		case ids[id] > 1:
+			invalid = append(invalid, InvalidOpinion{Object: item.object, Message: fmt.Sprintf("opinion %q is defined by both an Opinion and an OpinionProvider", id)})
+		default:
+			valid = append(valid, item)
+		}
+	}
+
+	valid, unorderable := orderable(builtin, valid)
+	for _, u := range unorderable {
+		invalid = append(invalid, InvalidOpinion{Object: u.item.object, Message: u.message})
+	}
+
+	sort.SliceStable(valid, func(i, j int) bool {
+		if valid[i].order != valid[j].order {
+			return valid[i].order < valid[j].order
+		}
+		return valid[i].opinion.GetId() < valid[j].opinion.GetId()
+	})
+	before, after := opinions.Opinions{}, opinions.Opinions{}
+	for _, item := range valid {
+		if item.order < 0 {
+			before = append(before, item.opinion)
+		} else {
+			after = append(after, item.opinion)
+		}
+	}
+	ordered := append(before, builtin...)
+	return append(ordered, after...), invalid
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/opinions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOpinions(t *testing.T) {
+	builtin := opinions.Opinions{
+		&opinions.BasicOpinion{Id: "spring-boot-actuator"},
+	}
+	opinionList := &OpinionList{
+		Items: []Opinion{
+			{ObjectMeta: metav1.ObjectMeta{Name: "first"}, Spec: OpinionSpec{Order: -1}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "last"}, Spec: OpinionSpec{Order: 10}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "middle"}},
+			// shadows a built-in opinion
+			{ObjectMeta: metav1.ObjectMeta{Name: "spring-boot-actuator"}},
+			// defined by both kinds
+			{ObjectMeta: metav1.ObjectMeta{Name: "shared"}},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+				Spec: OpinionSpec{
+					When: []OpinionCondition{{}},
+				},
+			},
+		},
+	}
+	providerList := &OpinionProviderList{
+		Items: []OpinionProvider{
+			{ObjectMeta: metav1.ObjectMeta{Name: "provider"}, Spec: OpinionProviderSpec{URL: "http://provider"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "shared"}, Spec: OpinionProviderSpec{URL: "http://shared"}},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid-provider"},
+				Spec: OpinionProviderSpec{
+					URL:  "http://invalid",
+					When: []OpinionCondition{{DependencyConstraint: &DependencyConstraint{Name: "spring-boot", Constraint: "not a constraint"}}},
+				},
+			},
+		},
+	}
+
+	ordered, invalid := Opinions(builtin, opinionList, providerList)
+	ids := []string{}
+	for _, o := range ordered {
+		ids = append(ids, o.GetId())
+	}
+	if diff := cmp.Diff([]string{"first", "spring-boot-actuator", "middle", "provider", "last"}, ids); diff != "" {
+		t.Errorf("opinions (-expected, +actual) = %v", diff)
+	}
+	invalidNames := []string{}
+	for _, i := range invalid {
+		invalidNames = append(invalidNames, i.Object.(metav1.Object).GetName())
+	}
+	if diff := cmp.Diff([]string{"invalid", "invalid-provider", "spring-boot-actuator", "shared", "shared"}, invalidNames); diff != "" {
+		t.Errorf("invalid opinions (-expected, +actual) = %v", diff)
+	}
+	if invalid[2].Object != &opinionList.Items[3] {
+		t.Errorf("expected the Opinion shadowing the built-in opinion to be reported")
+	}
+}
+
+func TestOpinions_Unorderable(t *testing.T) {
+	builtin := opinions.Opinions{
+		&opinions.BasicOpinion{Id: "spring-boot"},
+	}
+	opinionList := &OpinionList{
+		Items: []Opinion{
+			{ObjectMeta: metav1.ObjectMeta{Name: "extends-builtin"}, Spec: OpinionSpec{DependsOn: []string{"spring-boot"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "extends-custom"}, Spec: OpinionSpec{After: []string{"extends-builtin"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "unknown"}, Spec: OpinionSpec{DependsOn: []string{"deleted"}}},
+			// unknown once the opinion it depends on is dropped
+			{ObjectMeta: metav1.ObjectMeta{Name: "extends-unknown"}, Spec: OpinionSpec{DependsOn: []string{"unknown"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "chicken"}, Spec: OpinionSpec{DependsOn: []string{"egg"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "egg"}, Spec: OpinionSpec{After: []string{"chicken"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "omelette"}, Spec: OpinionSpec{DependsOn: []string{"egg"}}},
+		},
+	}
+	providerList := &OpinionProviderList{
+		Items: []OpinionProvider{
+			{ObjectMeta: metav1.ObjectMeta{Name: "self"}, Spec: OpinionProviderSpec{URL: "http://self", DependsOn: []string{"self"}}},
+		},
+	}
+
+	ordered, invalid := Opinions(builtin, opinionList, providerList)
+	if _, err := ordered.Sorted(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	ids := []string{}
+	for _, o := range ordered {
+		ids = append(ids, o.GetId())
+	}
+	if diff := cmp.Diff([]string{"spring-boot", "extends-builtin", "extends-custom"}, ids); diff != "" {
+		t.Errorf("opinions (-expected, +actual) = %v", diff)
+	}
+	messages := map[string]string{}
+	for _, i := range invalid {
+		messages[i.Object.(metav1.Object).GetName()] = i.Message
+	}
+	if diff := cmp.Diff(map[string]string{
+		"unknown":         `opinion "unknown" depends on or is after unknown opinions deleted`,
+		"chicken":         `opinion "chicken" can't be ordered, its dependencies form a cycle`,
+		"egg":             `opinion "egg" can't be ordered, its dependencies form a cycle`,
+		"self":            `opinion "self" can't be ordered, its dependencies form a cycle`,
+		"extends-unknown": `opinion "extends-unknown" depends on or is after unknown opinions unknown`,
+		"omelette":        `opinion "omelette" depends on or is after unknown opinions egg`,
+	}, messages); diff != "" {
+		t.Errorf("invalid opinions (-expected, +actual) = %v", diff)
+	}
+}
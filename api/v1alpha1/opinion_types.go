@@ -0,0 +1,151 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OpinionSpec defines the effects of an opinion on applications, and when
+// the opinion applies
+type OpinionSpec struct {
+	// Order of the opinion relative to the built-in opinions and other
+	// Opinions. Opinions with a negative order are applied before the
+	// built-in opinions, others after. Opinions with the same order are
+	// applied by name.
+	// +optional
+	Order int32 `json:"order,omitempty"`
+
+	// DependsOn lists the IDs of opinions that must be applied before the
+	// opinion. The opinion is not applied unless every one of them was
+	// applied.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// After lists the IDs of opinions that are applied before the opinion,
+	// when they are applied at all
+	// +optional
+	After []string `json:"after,omitempty"`
+
+	// When lists the conditions that must all hold for the opinion to apply.
+	// The opinion applies to every application when empty.
+	// +optional
+	When []OpinionCondition `json:"when,omitempty"`
+
+	// Effects of the opinion on applications
+	Effects OpinionEffects `json:"effects"`
+}
+
+// OpinionCondition is a condition of an application's image or application
+// properties. Exactly one field must be set.
+type OpinionCondition struct {
+	// Dependencies holds when the application has any of the dependencies
+	// +optional
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// DependencyConstraint holds when the application has the dependency,
+	// at a version satisfying the constraint
+	// +optional
+	DependencyConstraint *DependencyConstraint `json:"dependencyConstraint,omitempty"`
+
+	// Property holds when the application property has the value
+	// +optional
+	Property *PropertyCondition `json:"property,omitempty"`
+}
+
+// DependencyConstraint matches the version of a dependency
+type DependencyConstraint struct {
+	// Name of the dependency
+	Name string `json:"name"`
+
+	// Constraint of the dependency version, like `>= 2.3.0-0`, see
+	// https://github.com/Masterminds/semver#checking-version-constraints
+	Constraint string `json:"constraint"`
+}
+
+// PropertyCondition matches the value of an application property
+type PropertyCondition struct {
+	// Key of the application property
+	Key string `json:"key"`
+
+	// Value the property must have. The property must be set, to any value,
+	// when empty.
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+// OpinionEffects are applied to the application's pod template and target
+// container. Except for labels and annotations, effects only set defaults and
+// never override what the application already defines.
+type OpinionEffects struct {
+	// Labels to set on the application and its pod template
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations to set on the application and its pod template
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// ApplicationProperties to default
+	// +optional
+	ApplicationProperties map[string]string `json:"applicationProperties,omitempty"`
+
+	// Env vars to default on the target container
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// LivenessProbe of the target container, when it has none
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+
+	// ReadinessProbe of the target container, when it has none
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+
+	// Resources to default on the target container, for each resource that
+	// has no request or limit
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Order",type=integer,JSONPath=`.spec.order`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Opinion is the Schema for the opinions API. Opinions are applied to every
+// SpringBootApplication they hold for, along with the built-in opinions.
+type Opinion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec OpinionSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OpinionList contains a list of Opinion
+type OpinionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Opinion `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Opinion{}, &OpinionList{})
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	"github.com/spring-cloud-incubator/mononoke/opinions"
+)
+
+const defaultOpinionProviderTimeout = 10 * time.Second
+
+// Opinion converts the provider to an opinion applied by its webhook,
+// identified by the resource's name. The webhook is only called for
+// applications the provider's conditions hold for.
+func (p *OpinionProvider) Opinion() (opinions.Opinion, error) {
+	if err := validateConditions(p.Name, p.Spec.When); err != nil {
+		return nil, err
+	}
+	timeout := defaultOpinionProviderTimeout
+	if p.Spec.TimeoutSeconds != nil {
+		timeout = time.Duration(*p.Spec.TimeoutSeconds) * time.Second
+	}
+	failurePolicy := opinions.FailPolicy
+	if p.Spec.FailurePolicy != "" {
+		failurePolicy = opinions.FailurePolicy(p.Spec.FailurePolicy)
+	}
+	when := append([]OpinionCondition{}, p.Spec.When...)
+	return &opinions.WebhookOpinion{
+		Id:           p.Name,
+		DependsOn:    append([]string{}, p.Spec.DependsOn...),
+		After:        append([]string{}, p.Spec.After...),
+		Dependencies: conditionDependencies(when),
+		ApplicableFunc: func(ctx context.Context, applied opinions.AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			return conditionsHold(ctx, when, imageMetadata)
+		},
+		URL:           p.Spec.URL,
+		CABundle:      append([]byte{}, p.Spec.CABundle...),
+		Timeout:       timeout,
+		FailurePolicy: failurePolicy,
+	}, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OpinionProviderSpec defines the webhook that applies an opinion, and how the
+// opinion is ordered among other opinions
+type OpinionProviderSpec struct {
+	// Order of the opinion relative to the built-in opinions and other
+	// Opinions and OpinionProviders. Opinions with a negative order are
+	// applied before the built-in opinions, others after. Opinions with the
+	// same order are applied by name.
+	// +optional
+	Order int32 `json:"order,omitempty"`
+
+	// DependsOn lists the IDs of opinions that must be applied before the
+	// opinion. The opinion is not applied unless every one of them was
+	// applied.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// After lists the IDs of opinions that are applied before the opinion,
+	// when they are applied at all
+	// +optional
+	After []string `json:"after,omitempty"`
+
+	// When lists the conditions that must all hold for the webhook to be
+	// called. The webhook is called for every application when empty.
+	// +optional
+	When []OpinionCondition `json:"when,omitempty"`
+
+	// URL of the webhook, the http or https scheme is required
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// CABundle is the PEM encoded CA certificates that sign the webhook's
+	// certificate. The system's certificates are trusted when empty.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// TimeoutSeconds to wait for the webhook to respond, 10 seconds by
+	// default
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=30
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// FailurePolicy when the webhook fails or returns an invalid response,
+	// `Fail` to fail applying opinions, or `Ignore` to skip the opinion.
+	// Defaults to `Fail`.
+	// +kubebuilder:validation:Enum=Fail;Ignore
+	// +optional
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.url`
+// +kubebuilder:printcolumn:name="Order",type=integer,JSONPath=`.spec.order`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// OpinionProvider is the Schema for the opinionproviders API. The webhook of
+// an OpinionProvider is called to apply an opinion to every
+// SpringBootApplication, along with the built-in opinions.
+type OpinionProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec OpinionProviderSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OpinionProviderList contains a list of OpinionProvider
+type OpinionProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OpinionProvider `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OpinionProvider{}, &OpinionProviderList{})
+}
@@ -23,12 +23,26 @@ import (
 )
 
 const (
-	SpringBootApplicationConditionReady                              = apis.ConditionReady
-	SpringBootApplicationConditionDeploymentReady apis.ConditionType = "DeploymentReady"
+	SpringBootApplicationConditionReady                                        = apis.ConditionReady
+	SpringBootApplicationConditionImageResolved             apis.ConditionType = "ImageResolved"
+	SpringBootApplicationConditionSignatureVerified         apis.ConditionType = "SignatureVerified"
+	SpringBootApplicationConditionLicenseCompliant          apis.ConditionType = "LicenseCompliant"
+	SpringBootApplicationConditionBuildpacksTrusted         apis.ConditionType = "BuildpacksTrusted"
+	SpringBootApplicationConditionVulnerabilitiesAcceptable apis.ConditionType = "VulnerabilitiesAcceptable"
+	SpringBootApplicationConditionOpinionsApplied           apis.ConditionType = "OpinionsApplied"
+	SpringBootApplicationConditionDeploymentReady           apis.ConditionType = "DeploymentReady"
+	SpringBootApplicationConditionSBOMReady                 apis.ConditionType = "SBOMReady"
 )
 
 var springbootappCondSet = apis.NewLivingConditionSet(
+	SpringBootApplicationConditionImageResolved,
+	SpringBootApplicationConditionSignatureVerified,
+	SpringBootApplicationConditionLicenseCompliant,
+	SpringBootApplicationConditionBuildpacksTrusted,
+	SpringBootApplicationConditionVulnerabilitiesAcceptable,
+	SpringBootApplicationConditionOpinionsApplied,
 	SpringBootApplicationConditionDeploymentReady,
+	SpringBootApplicationConditionSBOMReady,
 )
 
 func (rs *SpringBootApplicationStatus) GetObservedGeneration() int64 {
@@ -51,10 +65,94 @@ func (rs *SpringBootApplicationStatus) InitializeConditions() {
 	springbootappCondSet.Manage(rs).InitializeConditions()
 }
 
+func (rs *SpringBootApplicationStatus) MarkTargetContainerNotFound(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionImageResolved, "TargetContainerNotFound", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkImageInvalid(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionImageResolved, "ImageInvalid", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkImageCredentialsMissing(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionImageResolved, "CredentialsMissing", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkProcessTypeNotFound(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionImageResolved, "ProcessTypeNotFound", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkImageResolved() {
+	springbootappCondSet.Manage(rs).MarkTrue(SpringBootApplicationConditionImageResolved)
+}
+
+func (rs *SpringBootApplicationStatus) MarkSignatureMissing(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionSignatureVerified, "SignatureMissing", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkSignatureInvalid(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionSignatureVerified, "SignatureInvalid", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkSignaturePublicKeysInvalid(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionSignatureVerified, "PublicKeysInvalid", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkSignatureVerified() {
+	springbootappCondSet.Manage(rs).MarkTrue(SpringBootApplicationConditionSignatureVerified)
+}
+
+func (rs *SpringBootApplicationStatus) MarkLicenseViolation(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionLicenseCompliant, "LicenseViolation", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkLicenseCompliant() {
+	springbootappCondSet.Manage(rs).MarkTrue(SpringBootApplicationConditionLicenseCompliant)
+}
+
+func (rs *SpringBootApplicationStatus) MarkUntrustedBuildpacks(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionBuildpacksTrusted, "UntrustedBuildpack", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkBuildpacksTrusted() {
+	springbootappCondSet.Manage(rs).MarkTrue(SpringBootApplicationConditionBuildpacksTrusted)
+}
+
+func (rs *SpringBootApplicationStatus) MarkAdvisoriesUnavailable(message string) {
+	springbootappCondSet.Manage(rs).MarkUnknown(SpringBootApplicationConditionVulnerabilitiesAcceptable, "AdvisoriesUnavailable", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkVulnerabilitiesFound(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionVulnerabilitiesAcceptable, "VulnerabilitiesFound", message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkVulnerabilitiesAcceptable() {
+	springbootappCondSet.Manage(rs).MarkTrue(SpringBootApplicationConditionVulnerabilitiesAcceptable)
+}
+
+func (rs *SpringBootApplicationStatus) MarkOpinionsFailed(reason, message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionOpinionsApplied, reason, message)
+}
+
+func (rs *SpringBootApplicationStatus) MarkOpinionsApplied() {
+	springbootappCondSet.Manage(rs).MarkTrue(SpringBootApplicationConditionOpinionsApplied)
+}
+
 func (rs *SpringBootApplicationStatus) MarkDeploymentNotOwned(name string) {
 	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionDeploymentReady, "NotOwned", "There is an existing Deployer %q that the SpringBootApplication does not own.", name)
 }
 
+func (rs *SpringBootApplicationStatus) MarkSBOMReady() {
+	springbootappCondSet.Manage(rs).MarkTrue(SpringBootApplicationConditionSBOMReady)
+}
+
+func (rs *SpringBootApplicationStatus) MarkSBOMNotOwned(name string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionSBOMReady, "NotOwned", "There is an existing ConfigMap %q that the SpringBootApplication does not own.", name)
+}
+
+func (rs *SpringBootApplicationStatus) MarkSBOMTooLarge(message string) {
+	springbootappCondSet.Manage(rs).MarkFalse(SpringBootApplicationConditionSBOMReady, "SBOMTooLarge", message)
+}
+
 func (rs *SpringBootApplicationStatus) PropagateDeploymentStatus(crs *appsv1.DeploymentStatus) {
 	var available, progressing *appsv1.DeploymentCondition
 	for i := range crs.Conditions {
@@ -47,6 +47,35 @@ type SpringBootApplicationSpec struct {
 	// ApplicationProperties to be included in the target application container
 	// +optional
 	ApplicationProperties map[string]string `json:"applicationProperties,omitempty"`
+
+	// ProcessType of the image's launch processes to run in the target
+	// container. Defaults to the `web` process, when the image has one. The
+	// command and args of the target container take precedence over the
+	// process.
+	// +optional
+	ProcessType string `json:"processType,omitempty"`
+
+	// Opinions selects the opinions applied to the application. Every
+	// opinion is applied when empty.
+	// +optional
+	Opinions *OpinionSelection `json:"opinions,omitempty"`
+}
+
+// OpinionSelection selects opinions by their ID, and whether their decisions
+// are explained
+type OpinionSelection struct {
+	// Enabled lists the only opinions to apply, when set
+	// +optional
+	Enabled []string `json:"enabled,omitempty"`
+
+	// Disabled lists opinions not to apply
+	// +optional
+	Disabled []string `json:"disabled,omitempty"`
+
+	// Explain records why each opinion was or was not applied, and the
+	// changes each applied opinion made, in `status.opinionDecisions`
+	// +optional
+	Explain bool `json:"explain,omitempty"`
 }
 
 // SpringBootApplicationStatus defines the observed state of SpringBootApplication
@@ -60,8 +89,149 @@ type SpringBootApplicationStatus struct {
 	// +optional
 	TargetContainer string `json:"targetContainer,omitempty"`
 
+	// LatestImage is the image of the target container, pinned to the digest
+	// the opinions were applied for, once the image satisfied every policy
+	// +optional
+	LatestImage string `json:"latestImage,omitempty"`
+
+	// MetadataSource is how the metadata of the latest image was detected,
+	// `buildpack` when read from the labels recorded by the buildpack
+	// lifecycle, `jar` when detected from the jars in the image's layers or
+	// `none`
+	// +optional
+	MetadataSource string `json:"metadataSource,omitempty"`
+
+	// ProcessType is the launch process run by the target container
+	// +optional
+	ProcessType string `json:"processType,omitempty"`
+
+	// JavaVersion is the version of the Java runtime in the latest image
+	// +optional
+	JavaVersion string `json:"javaVersion,omitempty"`
+
+	// ImageChanges summarizes how the latest image differs from the image it
+	// replaced
+	// +optional
+	ImageChanges *ImageChanges `json:"imageChanges,omitempty"`
+
 	// AppliedOpinions lists opinions applied to the application
 	AppliedOpinions []string `json:"appliedOpinions,omitempty"`
+
+	// SkippedOpinions lists opinions that were not applied as they, or an
+	// opinion they depend on, were not selected by the application, or as
+	// their failure was ignored
+	// +optional
+	SkippedOpinions []SkippedOpinion `json:"skippedOpinions,omitempty"`
+
+	// OpinionDecisions explains why each opinion was or was not applied, when
+	// `spec.opinions.explain` is set
+	// +optional
+	OpinionDecisions []OpinionDecision `json:"opinionDecisions,omitempty"`
+
+	// SBOMConfigMap is the name of the ConfigMap holding the CycloneDX bill of
+	// materials of the latest image, under the `bom.json` key
+	// +optional
+	SBOMConfigMap string `json:"sbomConfigMap,omitempty"`
+
+	// Vulnerabilities lists up to 10 advisories that affect the dependencies
+	// of the latest image, most severe first
+	// +optional
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// SkippedOpinion is an opinion that was not applied
+type SkippedOpinion struct {
+	// ID of the opinion
+	ID string `json:"id"`
+
+	// Reason the opinion was skipped, `Disabled` when listed in
+	// `spec.opinions.disabled`, `NotEnabled` when missing from
+	// `spec.opinions.enabled`, `PrerequisiteSkipped` when an opinion it
+	// depends on was skipped, or `OpinionWebhookIgnored` when the webhook of
+	// an OpinionProvider failed and its failure policy is `Ignore`
+	Reason string `json:"reason"`
+
+	// Message describes the ignored failure
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// OpinionDecision explains why an opinion was or was not applied
+type OpinionDecision struct {
+	// ID of the opinion
+	ID string `json:"id"`
+
+	// Applied is true when the opinion was applied
+	Applied bool `json:"applied"`
+
+	// Reason the opinion was not applied, `NotApplicable` when the opinion
+	// doesn't apply to the image, `PrerequisiteNotApplied` when an opinion it
+	// depends on was not applied, or the reason it was skipped
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Dependencies of the image the opinion was applied for, like
+	// `spring-boot-actuator 2.3.0.RELEASE`
+	// +optional
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// DefaultedProperties are the application properties the opinion set
+	// +optional
+	DefaultedProperties map[string]string `json:"defaultedProperties,omitempty"`
+
+	// Patch is the JSON patch (RFC 6902) of the changes the opinion made to
+	// the pod template
+	// +optional
+	Patch string `json:"patch,omitempty"`
+}
+
+// ImageChanges summarizes the changes between two images of an application
+type ImageChanges struct {
+	// PreviousImage is the image the latest image replaced
+	PreviousImage string `json:"previousImage"`
+
+	// Java summarizes the change of the Java runtime version
+	// +optional
+	Java string `json:"java,omitempty"`
+
+	// Dependencies summarizes the dependencies that were added, removed or
+	// changed version, like `spring-boot 2.2.5 → 2.3.0, +spring-boot-starter-actuator`
+	// +optional
+	Dependencies string `json:"dependencies,omitempty"`
+
+	// Buildpacks summarizes the buildpacks that were added, removed or
+	// changed version
+	// +optional
+	Buildpacks string `json:"buildpacks,omitempty"`
+}
+
+// Vulnerability is an advisory that affects a dependency of the application
+type Vulnerability struct {
+	// ID of the advisory
+	ID string `json:"id"`
+
+	// Aliases of the advisory, like its CVE identifier
+	// +optional
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Summary of the advisory
+	// +optional
+	Summary string `json:"summary,omitempty"`
+
+	// Severity of the advisory, one of `CRITICAL`, `HIGH`, `MEDIUM`, `LOW` or
+	// `UNKNOWN`
+	Severity string `json:"severity"`
+
+	// Dependency affected by the advisory
+	Dependency string `json:"dependency"`
+
+	// Version of the affected dependency
+	Version string `json:"version"`
+
+	// FixedVersion is the version of the dependency the advisory is fixed in,
+	// if known
+	// +optional
+	FixedVersion string `json:"fixedVersion,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,316 @@
+package cnb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severity of a vulnerability
+type Severity string
+
+const (
+	UnknownSeverity  Severity = "UNKNOWN"
+	LowSeverity      Severity = "LOW"
+	MediumSeverity   Severity = "MEDIUM"
+	HighSeverity     Severity = "HIGH"
+	CriticalSeverity Severity = "CRITICAL"
+)
+
+var severityRanks = map[Severity]int{
+	UnknownSeverity:  0,
+	LowSeverity:      1,
+	MediumSeverity:   2,
+	HighSeverity:     3,
+	CriticalSeverity: 4,
+}
+
+// ParseSeverity parses a severity name, `MODERATE` is an alias for `MEDIUM`
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToUpper(s))
+	if severity == "MODERATE" {
+		severity = MediumSeverity
+	}
+	if _, ok := severityRanks[severity]; !ok {
+		return "", fmt.Errorf("unknown severity %q", s)
+	}
+	return severity, nil
+}
+
+// AtLeast returns true if the severity is the same as or more severe than the
+// threshold
+func (s Severity) AtLeast(threshold Severity) bool {
+	return severityRanks[s] >= severityRanks[threshold]
+}
+
+// Advisory is a vulnerability advisory in the OSV format, see
+// https://ossf.github.io/osv-schema/
+type Advisory struct {
+	ID       string             `json:"id"`
+	Summary  string             `json:"summary"`
+	Aliases  []string           `json:"aliases"`
+	Affected []AdvisoryAffected `json:"affected"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// AdvisoryAffected is a package affected by an advisory
+type AdvisoryAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+		PURL      string `json:"purl"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced   string `json:"introduced"`
+			Fixed        string `json:"fixed"`
+			LastAffected string `json:"last_affected"`
+		} `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+}
+
+// GetSeverity returns the severity the database that published the advisory
+// assigned, or the severity calculated from the advisory's CVSS v3 vector
+func (a *Advisory) GetSeverity() Severity {
+	if s, err := ParseSeverity(a.DatabaseSpecific.Severity); err == nil {
+		return s
+	}
+	for _, s := range a.Severity {
+		if s.Type != "CVSS_V3" {
+			continue
+		}
+		if score, err := CVSS3BaseScore(s.Score); err == nil {
+			return cvssSeverity(score)
+		}
+	}
+	return UnknownSeverity
+}
+
+// affects returns the fixed version, if any, and true if the version of the
+// package is affected
+func (a *AdvisoryAffected) affects(version string) (string, bool) {
+	for _, v := range a.Versions {
+		if CompareVersions(v, version) == 0 {
+			return "", true
+		}
+	}
+	for _, r := range a.Ranges {
+		if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+			continue
+		}
+		affected, fixed := false, ""
+		for _, e := range r.Events {
+			switch {
+			case e.Introduced != "":
+				if e.Introduced == "0" || CompareVersions(version, e.Introduced) >= 0 {
+					affected = true
+				}
+			case e.Fixed != "":
+				if CompareVersions(version, e.Fixed) >= 0 {
+					affected = false
+				} else if affected && fixed == "" {
+					fixed = e.Fixed
+				}
+			case e.LastAffected != "":
+				if CompareVersions(version, e.LastAffected) > 0 {
+					affected = false
+				}
+			}
+		}
+		if affected {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+// Vulnerability is a dependency affected by an advisory
+type Vulnerability struct {
+	Advisory   *Advisory
+	Dependency Dependency
+	Severity   Severity
+	// Fixed is the version the vulnerability is fixed in, if known
+	Fixed string
+}
+
+// AdvisoryDatabase matches dependencies against a set of advisories
+type AdvisoryDatabase struct {
+	// advisories by ecosystem and lower case package name
+	advisories map[string][]*Advisory
+	// maven advisories by lower case artifact id
+	artifacts map[string][]*Advisory
+}
+
+// NewAdvisoryDatabase creates an empty advisory database
+func NewAdvisoryDatabase() *AdvisoryDatabase {
+	return &AdvisoryDatabase{
+		advisories: map[string][]*Advisory{},
+		artifacts:  map[string][]*Advisory{},
+	}
+}
+
+// LoadAdvisoryDirectory reads every `.json` file in the directory tree as an
+// OSV advisory, skipping hidden directories
+func LoadAdvisoryDirectory(dir string) (*AdvisoryDatabase, error) {
+	db := NewAdvisoryDatabase()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// volumes mounted from a ConfigMap hold each file in a hidden
+			// directory, as well as a link to the file
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".json" {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := db.Add(content); err != nil {
+			return fmt.Errorf("invalid advisory %s: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Add parses an OSV advisory and adds it to the database
+func (db *AdvisoryDatabase) Add(content []byte) error {
+	advisory := &Advisory{}
+	if err := json.Unmarshal(content, advisory); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, affected := range advisory.Affected {
+		key := advisoryKey(affected.Package.Ecosystem, affected.Package.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		db.advisories[key] = append(db.advisories[key], advisory)
+		if affected.Package.Ecosystem == "Maven" {
+			name := affected.Package.Name
+			artifact := strings.ToLower(name[strings.LastIndex(name, ":")+1:])
+			db.artifacts[artifact] = append(db.artifacts[artifact], advisory)
+		}
+	}
+	return nil
+}
+
+// Match returns the vulnerabilities of the dependencies, ordered by severity
+func (db *AdvisoryDatabase) Match(dependencies []Dependency) []Vulnerability {
+	vulnerabilities := []Vulnerability{}
+	for _, d := range dependencies {
+		if d.Version == "" {
+			continue
+		}
+		ecosystem, name, ok := advisoryPackage(d)
+		if !ok {
+			continue
+		}
+		for _, advisory := range db.candidates(ecosystem, name) {
+			for _, affected := range advisory.Affected {
+				if !packageMatches(affected.Package.Ecosystem, affected.Package.Name, ecosystem, name) {
+					continue
+				}
+				if fixed, ok := affected.affects(d.Version); ok {
+					vulnerabilities = append(vulnerabilities, Vulnerability{
+						Advisory:   advisory,
+						Dependency: d,
+						Severity:   advisory.GetSeverity(),
+						Fixed:      fixed,
+					})
+					break
+				}
+			}
+		}
+	}
+	sort.SliceStable(vulnerabilities, func(i, j int) bool {
+		return severityRanks[vulnerabilities[i].Severity] > severityRanks[vulnerabilities[j].Severity]
+	})
+	return vulnerabilities
+}
+
+func (db *AdvisoryDatabase) candidates(ecosystem, name string) []*Advisory {
+	if ecosystem == "Maven" && !strings.Contains(name, ":") {
+		// without a group id, match the artifact id of every maven package.
+		// The group id is known for jars with a pom.properties file, so only
+		// libraries without coordinates fall back to the artifact id.
+		return db.artifacts[strings.ToLower(name)]
+	}
+	return db.advisories[advisoryKey(ecosystem, name)]
+}
+
+func packageMatches(advisoryEcosystem, advisoryName, ecosystem, name string) bool {
+	if advisoryEcosystem != ecosystem {
+		return false
+	}
+	if ecosystem == "Maven" && !strings.Contains(name, ":") {
+		advisoryName = advisoryName[strings.LastIndex(advisoryName, ":")+1:]
+	}
+	return strings.EqualFold(advisoryName, name)
+}
+
+func advisoryKey(ecosystem, name string) string {
+	return ecosystem + "/" + strings.ToLower(name)
+}
+
+// osvEcosystems maps package URL types to OSV ecosystems
+var osvEcosystems = map[string]string{
+	"maven":  "Maven",
+	"npm":    "npm",
+	"pypi":   "PyPI",
+	"golang": "Go",
+	"gem":    "RubyGems",
+	"nuget":  "NuGet",
+	"cargo":  "crates.io",
+}
+
+// advisoryPackage returns the OSV ecosystem and package name of the
+// dependency. Libraries without a package URL are assumed to be jars, named
+// by their artifact id.
+func advisoryPackage(d Dependency) (string, string, bool) {
+	if d.PURL == "" {
+		return "Maven", d.Name, d.Kind == LibraryDependency
+	}
+	ecosystem, ok := osvEcosystems[purlType(d.PURL)]
+	if !ok {
+		return "", "", false
+	}
+	// pkg:type/namespace/name@version?qualifiers#subpath
+	p := strings.TrimPrefix(d.PURL, "pkg:")
+	p = p[strings.Index(p, "/")+1:]
+	for _, sep := range []string{"#", "?", "@"} {
+		if i := strings.Index(p, sep); i >= 0 {
+			p = p[:i]
+		}
+	}
+	switch ecosystem {
+	case "Maven":
+		p = strings.Replace(p, "/", ":", 1)
+	case "npm":
+		p = strings.Replace(p, "%40", "@", 1)
+	}
+	return ecosystem, p, true
+}
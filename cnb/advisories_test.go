@@ -0,0 +1,143 @@
+package cnb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testAdvisory = `{
+  "id": "GHSA-36p3-wjmg-h94x",
+  "summary": "Remote Code Execution in Spring Framework",
+  "aliases": ["CVE-2022-22965"],
+  "affected": [{
+    "package": {"ecosystem": "Maven", "name": "org.springframework:spring-beans"},
+    "ranges": [{
+      "type": "ECOSYSTEM",
+      "events": [{"introduced": "0"}, {"fixed": "5.2.20.RELEASE"}, {"introduced": "5.3.0"}, {"fixed": "5.3.18"}]
+    }]
+  }],
+  "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}]
+}`
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "1.0", b: "1.0.0", expected: 0},
+		{a: "1.0.RELEASE", b: "1.0", expected: 0},
+		{a: "1.2", b: "1.10", expected: -1},
+		{a: "2.2.5.RELEASE", b: "2.2.10.RELEASE", expected: -1},
+		{a: "5.3.0-M1", b: "5.3.0-RC1", expected: -1},
+		{a: "5.3.0-RC1", b: "5.3.0", expected: -1},
+		{a: "5.3.0-SNAPSHOT", b: "5.3.0", expected: -1},
+		{a: "1.0-SP1", b: "1.0", expected: 1},
+		{a: "1.0.1", b: "1.0-SP1", expected: 1},
+		{a: "v1.2.3", b: "1.2.3", expected: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.a+" "+test.b, func(t *testing.T) {
+			if actual := CompareVersions(test.a, test.b); actual != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, actual)
+			}
+			if actual := CompareVersions(test.b, test.a); actual != -test.expected {
+				t.Errorf("expected %d reversed, got %d", -test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCVSS3BaseScore(t *testing.T) {
+	tests := []struct {
+		vector   string
+		expected float64
+	}{
+		{vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", expected: 9.8},
+		{vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", expected: 10.0},
+		{vector: "CVSS:3.0/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N", expected: 5.9},
+		{vector: "CVSS:3.1/AV:N/AC:L/PR:L/UI:R/S:C/C:L/I:L/A:N", expected: 5.4},
+		{vector: "CVSS:3.1/AV:L/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", expected: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.vector, func(t *testing.T) {
+			actual, err := CVSS3BaseScore(test.vector)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+
+	if _, err := CVSS3BaseScore("AV:N/AC:L/Au:N/C:P/I:P/A:P"); err == nil {
+		t.Errorf("expected error for cvss v2 vector")
+	}
+}
+
+func TestAdvisoryDatabase_Match(t *testing.T) {
+	db := NewAdvisoryDatabase()
+	if err := db.Add([]byte(testAdvisory)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	dependencies := []Dependency{
+		{Name: "spring-beans", Version: "5.2.3.RELEASE", Kind: LibraryDependency},
+		{Name: "spring-beans", Version: "5.2.20.RELEASE", Kind: LibraryDependency},
+		{Name: "spring-beans", Version: "5.3.17", PURL: "pkg:maven/org.springframework/spring-beans@5.3.17", Kind: LibraryDependency},
+		{Name: "spring-beans", Version: "5.3.18", PURL: "pkg:maven/org.springframework/spring-beans@5.3.18", Kind: LibraryDependency},
+		// the group of the library is known, and differs from the advisory's
+		{Name: "spring-beans", Version: "5.3.17", PURL: "pkg:maven/com.example/spring-beans@5.3.17", Kind: LibraryDependency},
+		{Name: "com.example:spring-beans", Version: "5.3.17", Kind: LibraryDependency},
+		{Name: "spring-beans", Version: "5.2.3", Kind: RuntimeDependency},
+	}
+	vulnerabilities := db.Match(dependencies)
+	if len(vulnerabilities) != 2 {
+		t.Fatalf("expected 2 vulnerabilities, got %v", vulnerabilities)
+	}
+	for i, expected := range []struct{ version, fixed string }{{"5.2.3.RELEASE", "5.2.20.RELEASE"}, {"5.3.17", "5.3.18"}} {
+		v := vulnerabilities[i]
+		if v.Advisory.ID != "GHSA-36p3-wjmg-h94x" || v.Dependency.Version != expected.version || v.Fixed != expected.fixed || v.Severity != CriticalSeverity {
+			t.Errorf("unexpected vulnerability %v", v)
+		}
+	}
+}
+
+func TestAdvisory_GetSeverity(t *testing.T) {
+	a := &Advisory{}
+	if s := a.GetSeverity(); s != UnknownSeverity {
+		t.Errorf("expected %s, got %s", UnknownSeverity, s)
+	}
+	a.DatabaseSpecific.Severity = "MODERATE"
+	if s := a.GetSeverity(); s != MediumSeverity {
+		t.Errorf("expected %s, got %s", MediumSeverity, s)
+	}
+	if !HighSeverity.AtLeast(MediumSeverity) || LowSeverity.AtLeast(MediumSeverity) {
+		t.Errorf("unexpected severity ordering")
+	}
+}
+
+func TestLoadAdvisoryDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "advisories")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, path := range []string{"maven/GHSA-36p3-wjmg-h94x.json", "..2020_03_10/GHSA-36p3-wjmg-h94x.json"} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(testAdvisory), 0644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	db, err := LoadAdvisoryDirectory(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if vulnerabilities := db.Match([]Dependency{{Name: "spring-beans", Version: "5.3.0", Kind: LibraryDependency}}); len(vulnerabilities) != 1 {
+		t.Errorf("expected 1 vulnerability, got %v", vulnerabilities)
+	}
+}
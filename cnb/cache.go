@@ -0,0 +1,166 @@
+package cnb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+// MetadataCache resolves the ImageMetadata for an image reference. Parsed
+// metadata is cached by the image's digest, which is immutable, while the
+// digest a tag points to is cached for a limited time so that tags that move
+// are eventually observed. Entries are scoped to the credentials the image
+// was resolved with, so a private image resolved by one caller is not served
+// to callers that can't pull it.
+type MetadataCache struct {
+	Registry Registry
+
+	tagTTL   time.Duration
+	tags     *cache.LRUExpireCache
+	metadata *lru.Cache
+
+	hits   *prometheus.CounterVec
+	misses *prometheus.CounterVec
+}
+
+func NewMetadataCache(registry Registry, size int, tagTTL time.Duration) *MetadataCache {
+	metadata, err := lru.New(size)
+	if err != nil {
+		// if called with an invalid size
+		panic(err)
+	}
+	return &MetadataCache{
+		Registry: registry,
+
+		tagTTL:   tagTTL,
+		tags:     cache.NewLRUExpireCache(size),
+		metadata: metadata,
+
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mononoke_image_metadata_cache_hits_total",
+			Help: "Number of image metadata lookups served from the cache",
+		}, []string{"cache"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mononoke_image_metadata_cache_misses_total",
+			Help: "Number of image metadata lookups that required the registry",
+		}, []string{"cache"}),
+	}
+}
+
+// Collectors returns the cache's hit and miss metrics to be registered with a
+// prometheus registry.
+func (c *MetadataCache) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.hits, c.misses}
+}
+
+// ResolveDigest returns the reference pinned to the image's digest. Only the
+// image's manifest is fetched, so the image can be verified before its
+// config and layers are read.
+func (c *MetadataCache) ResolveDigest(ref string, keychain authn.Keychain) (string, error) {
+	parsed, err := name.ParseReference(ref, name.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+	credentials, err := credentialKey(parsed.Context(), keychain)
+	if err != nil {
+		return "", err
+	}
+	digest, _, err := c.resolveDigest(parsed, credentials, keychain)
+	if err != nil {
+		return "", err
+	}
+	return digestedReference(parsed, digest), nil
+}
+
+// Resolve returns the reference pinned to the image's digest along with the
+// image's ImageMetadata. The registry is only consulted when the digest a tag
+// points to is unknown or expired, and the image's config is only fetched for
+// digests that have not been seen before with the same credentials.
+func (c *MetadataCache) Resolve(ref string, keychain authn.Keychain) (string, ImageMetadata, error) {
+	parsed, err := name.ParseReference(ref, name.WeakValidation)
+	if err != nil {
+		return "", ImageMetadata{}, err
+	}
+	credentials, err := credentialKey(parsed.Context(), keychain)
+	if err != nil {
+		return "", ImageMetadata{}, err
+	}
+	digest, img, err := c.resolveDigest(parsed, credentials, keychain)
+	if err != nil {
+		return "", ImageMetadata{}, err
+	}
+	if md, ok := c.metadata.Get(credentials + digest); ok {
+		c.hits.WithLabelValues("metadata").Inc()
+		return digestedReference(parsed, digest), md.(ImageMetadata), nil
+	}
+
+	c.misses.WithLabelValues("metadata").Inc()
+	if img == nil {
+		img, err = c.Registry.GetImage(digestedReference(parsed, digest), keychain)
+		if err != nil {
+			return "", ImageMetadata{}, err
+		}
+	}
+	md, err := ParseImageMetadata(img)
+	if err != nil {
+		return "", ImageMetadata{}, err
+	}
+	c.metadata.Add(credentials+digest, md)
+	return digestedReference(parsed, digest), md, nil
+}
+
+// resolveDigest returns the digest of the image, along with the image when it
+// had to be fetched from the registry to learn the digest
+func (c *MetadataCache) resolveDigest(parsed name.Reference, credentials string, keychain authn.Keychain) (string, v1.Image, error) {
+	if d, ok := parsed.(name.Digest); ok {
+		return d.DigestStr(), nil, nil
+	}
+	if d, ok := c.tags.Get(credentials + parsed.Name()); ok {
+		c.hits.WithLabelValues("tag").Inc()
+		return d.(string), nil, nil
+	}
+	c.misses.WithLabelValues("tag").Inc()
+
+	img, err := c.Registry.GetImage(parsed.Name(), keychain)
+	if err != nil {
+		return "", nil, err
+	}
+	hash, err := img.Digest()
+	if err != nil {
+		return "", nil, err
+	}
+	digest := hash.String()
+	c.tags.Add(credentials+parsed.Name(), digest, c.tagTTL)
+	return digest, img, nil
+}
+
+// credentialKey identifies the credentials the keychain provides for the
+// repository, as a prefix for cache keys. Lookups without a keychain use the
+// registry's own credentials.
+func credentialKey(repo name.Repository, keychain authn.Keychain) (string, error) {
+	if keychain == nil {
+		return "", nil
+	}
+	auth, err := keychain.Resolve(repo)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := auth.Authorization()
+	if err != nil {
+		return "", err
+	}
+	content, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]) + "/", nil
+}
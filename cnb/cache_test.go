@@ -0,0 +1,197 @@
+package cnb
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetadataCache(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ref := u.Host + "/repo/app:latest"
+	digest := pushImage(t, ref, testLabel)
+
+	c := NewMetadataCache(&RemoteRegistry{Keychain: authn.DefaultKeychain}, 10, time.Hour)
+
+	expectedRef := u.Host + "/repo/app@" + digest
+	assertResolve := func(ref string) {
+		t.Helper()
+		actualRef, md, err := c.Resolve(ref, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if actualRef != expectedRef {
+			t.Errorf("expected ref %q, got %q", expectedRef, actualRef)
+		}
+		if diff := cmp.Diff(md.Buildpacks, []Buildpack{
+			{ID: "com.example.buildpack.1", Version: "v1.2.3"},
+			{ID: "com.example.buildpack.2", Version: "v3.2.1"},
+		}); diff != "" {
+			t.Errorf("buildpacks (-expected, +actual) = %v", diff)
+		}
+	}
+	assertCounts := func(tagHits, tagMisses, metadataHits, metadataMisses float64) {
+		t.Helper()
+		if actual := testutil.ToFloat64(c.hits.WithLabelValues("tag")); actual != tagHits {
+			t.Errorf("expected %v tag hits, got %v", tagHits, actual)
+		}
+		if actual := testutil.ToFloat64(c.misses.WithLabelValues("tag")); actual != tagMisses {
+			t.Errorf("expected %v tag misses, got %v", tagMisses, actual)
+		}
+		if actual := testutil.ToFloat64(c.hits.WithLabelValues("metadata")); actual != metadataHits {
+			t.Errorf("expected %v metadata hits, got %v", metadataHits, actual)
+		}
+		if actual := testutil.ToFloat64(c.misses.WithLabelValues("metadata")); actual != metadataMisses {
+			t.Errorf("expected %v metadata misses, got %v", metadataMisses, actual)
+		}
+	}
+
+	assertResolve(ref)
+	assertCounts(0, 1, 0, 1)
+
+	assertResolve(ref)
+	assertCounts(1, 1, 1, 1)
+
+	// digested references never need to resolve a tag
+	assertResolve(expectedRef)
+	assertCounts(1, 1, 2, 1)
+
+	// a moved tag is not observed until the tag expires
+	pushImage(t, ref, "{}")
+	assertResolve(ref)
+	assertCounts(2, 1, 3, 1)
+}
+
+func TestMetadataCache_ResolveDigest(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ref := u.Host + "/repo/app:latest"
+	expectedRef := u.Host + "/repo/app@" + pushImage(t, ref, testLabel)
+
+	c := NewMetadataCache(&RemoteRegistry{Keychain: authn.DefaultKeychain}, 10, time.Hour)
+	actualRef, err := c.ResolveDigest(ref, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if actualRef != expectedRef {
+		t.Errorf("expected ref %q, got %q", expectedRef, actualRef)
+	}
+	// the metadata is not parsed until it is resolved
+	if actual := testutil.ToFloat64(c.misses.WithLabelValues("metadata")); actual != 0 {
+		t.Errorf("expected 0 metadata misses, got %v", actual)
+	}
+
+	if _, _, err := c.Resolve(actualRef, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := c.ResolveDigest(ref, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if actual := testutil.ToFloat64(c.hits.WithLabelValues("tag")); actual != 1 {
+		t.Errorf("expected 1 tag hit, got %v", actual)
+	}
+}
+
+func TestMetadataCache_Credentials(t *testing.T) {
+	authenticate := false
+	r := registry.New(registry.Logger(log.New(ioutil.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if user, password, ok := req.BasicAuth(); authenticate && (!ok || user != "user" || password != "secret") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ref := u.Host + "/repo/private:latest"
+	digest := pushImage(t, ref, testLabel)
+	authenticate = true
+
+	c := NewMetadataCache(&RemoteRegistry{Keychain: authn.DefaultKeychain}, 10, time.Hour)
+	authorized := testKeychain{&authn.Basic{Username: "user", Password: "secret"}}
+	unauthorized := testKeychain{authn.Anonymous}
+
+	if _, _, err := c.Resolve(ref, authorized); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// neither the tag nor the digest is served from the cache without the
+	// credentials the image was resolved with
+	for _, r := range []string{ref, u.Host + "/repo/private@" + digest} {
+		if _, _, err := c.Resolve(r, unauthorized); !IsUnauthorized(err) {
+			t.Errorf("expected unauthorized error resolving %s, got %v", r, err)
+		}
+	}
+	if _, _, err := c.Resolve(ref, authorized); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if actual := testutil.ToFloat64(c.hits.WithLabelValues("metadata")); actual != 1 {
+		t.Errorf("expected 1 metadata hit, got %v", actual)
+	}
+}
+
+type testKeychain struct {
+	authn.Authenticator
+}
+
+func (k testKeychain) Resolve(authn.Resource) (authn.Authenticator, error) {
+	return k.Authenticator, nil
+}
+
+func pushImage(t *testing.T, ref, label string) string {
+	t.Helper()
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	img, err = mutate.Config(img, v1.Config{
+		Labels: map[string]string{
+			"io.buildpacks.build.metadata": label,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tag, err := name.NewTag(ref, name.WeakValidation)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := remote.Write(tag, img); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return digest.String()
+}
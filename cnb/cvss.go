@@ -0,0 +1,103 @@
+package cnb
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// CVSS3BaseScore calculates the base score of a CVSS v3 vector, like
+// `CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H`, see
+// https://www.first.org/cvss/v3.1/specification-document
+func CVSS3BaseScore(vector string) (float64, error) {
+	parts := strings.Split(vector, "/")
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "CVSS:3") {
+		return 0, fmt.Errorf("invalid cvss v3 vector %q", vector)
+	}
+	metrics := map[string]string{}
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) != 2 {
+			return 0, fmt.Errorf("invalid cvss v3 vector %q", vector)
+		}
+		metrics[kv[0]] = kv[1]
+	}
+	weights := map[string]float64{}
+	for metric, values := range cvss3Weights {
+		w, ok := values[metrics[metric]]
+		if !ok {
+			return 0, fmt.Errorf("invalid cvss v3 vector %q, metric %s", vector, metric)
+		}
+		weights[metric] = w
+	}
+	changed := false
+	switch metrics["S"] {
+	case "U":
+	case "C":
+		changed = true
+	default:
+		return 0, fmt.Errorf("invalid cvss v3 vector %q, metric S", vector)
+	}
+	switch metrics["PR"] {
+	case "N":
+		weights["PR"] = 0.85
+	case "L":
+		weights["PR"] = 0.62
+		if changed {
+			weights["PR"] = 0.68
+		}
+	case "H":
+		weights["PR"] = 0.27
+		if changed {
+			weights["PR"] = 0.5
+		}
+	default:
+		return 0, fmt.Errorf("invalid cvss v3 vector %q, metric PR", vector)
+	}
+
+	iss := 1 - (1-weights["C"])*(1-weights["I"])*(1-weights["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	exploitability := 8.22 * weights["AV"] * weights["AC"] * weights["PR"] * weights["UI"]
+	if impact <= 0 {
+		return 0, nil
+	}
+	if changed {
+		return cvssRoundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return cvssRoundUp(math.Min(impact+exploitability, 10)), nil
+}
+
+// cvssRoundUp rounds up to one decimal place, avoiding floating point errors
+func cvssRoundUp(value float64) float64 {
+	i := int64(math.Round(value * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
+
+func cvssSeverity(score float64) Severity {
+	switch {
+	case score >= 9:
+		return CriticalSeverity
+	case score >= 7:
+		return HighSeverity
+	case score >= 4:
+		return MediumSeverity
+	case score > 0:
+		return LowSeverity
+	}
+	return UnknownSeverity
+}
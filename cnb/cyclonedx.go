@@ -0,0 +1,154 @@
+package cnb
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// CycloneDX is a CycloneDX 1.2 bill of materials, see
+// https://cyclonedx.org/docs/1.2/json/
+type CycloneDX struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    CycloneDXMetadata    `json:"metadata"`
+	Components  []CycloneDXComponent `json:"components"`
+}
+
+type CycloneDXMetadata struct {
+	Tools     []CycloneDXTool    `json:"tools,omitempty"`
+	Component CycloneDXComponent `json:"component"`
+}
+
+type CycloneDXTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type CycloneDXComponent struct {
+	BOMRef   string                   `json:"bom-ref,omitempty"`
+	Type     string                   `json:"type"`
+	Name     string                   `json:"name"`
+	Version  string                   `json:"version,omitempty"`
+	PURL     string                   `json:"purl,omitempty"`
+	Hashes   []CycloneDXHash          `json:"hashes,omitempty"`
+	Licenses []CycloneDXLicenseChoice `json:"licenses,omitempty"`
+	// Components nested within the component
+	Components []CycloneDXComponent `json:"components,omitempty"`
+}
+
+type CycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+// CycloneDXLicenseChoice is either a license or an SPDX license expression
+type CycloneDXLicenseChoice struct {
+	License    *CycloneDXLicense `json:"license,omitempty"`
+	Expression string            `json:"expression,omitempty"`
+}
+
+// CycloneDXLicense is a license by SPDX id, or by name
+type CycloneDXLicense struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+var (
+	spdxIDPattern         = regexp.MustCompile(`^[A-Za-z0-9.+-]+$`)
+	spdxExpressionPattern = regexp.MustCompile(`\s(?:AND|OR|WITH)\s`)
+)
+
+// NewCycloneDX describes the image as a CycloneDX bill of materials. The
+// buildpacks that built the image are listed as tools, and the stack, its
+// operating system packages, the runtime dependencies contributed by
+// buildpacks and the libraries of the application as components. The
+// document only depends on the image, so it's the same every time it's
+// generated for the image.
+func NewCycloneDX(ref string, md ImageMetadata) (*CycloneDX, error) {
+	digest, err := name.NewDigest(ref, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+	bom := &CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.2",
+		Version:     1,
+		Metadata: CycloneDXMetadata{
+			Tools: []CycloneDXTool{},
+			Component: CycloneDXComponent{
+				BOMRef:  ref,
+				Type:    "container",
+				Name:    digest.Context().Name(),
+				Version: digest.DigestStr(),
+			},
+		},
+		Components: []CycloneDXComponent{},
+	}
+	for _, bp := range md.Buildpacks {
+		bom.Metadata.Tools = append(bom.Metadata.Tools, CycloneDXTool{Name: bp.ID, Version: bp.Version})
+	}
+	if stack := md.Lifecycle.Stack; stack.ID != "" {
+		bom.Components = append(bom.Components, CycloneDXComponent{
+			BOMRef:  "stack/" + stack.ID,
+			Type:    "operating-system",
+			Name:    stack.ID,
+			Version: md.Lifecycle.RunImage.Reference,
+		})
+	}
+	seen := map[string]bool{}
+	for _, dependencies := range [][]Dependency{md.Packages, md.Dependencies} {
+		for _, d := range dependencies {
+			c := cycloneDXComponent(d)
+			if seen[c.BOMRef] {
+				// the same dependency may be reported by more than one buildpack
+				continue
+			}
+			seen[c.BOMRef] = true
+			bom.Components = append(bom.Components, c)
+		}
+	}
+	return bom, nil
+}
+
+// JSON encodes the bill of materials
+func (b *CycloneDX) JSON() ([]byte, error) {
+	return json.MarshalIndent(b, "", "  ")
+}
+
+func cycloneDXComponent(d Dependency) CycloneDXComponent {
+	c := CycloneDXComponent{
+		Type:    "library",
+		Name:    d.Name,
+		Version: d.Version,
+		PURL:    d.PURL,
+	}
+	if d.Kind == RuntimeDependency {
+		c.Type = "application"
+	}
+	c.BOMRef = d.PURL
+	if c.BOMRef == "" {
+		c.BOMRef = fmt.Sprintf("%s/%s@%s", d.Kind, d.Name, d.Version)
+	}
+	if d.Sha256 != "" {
+		c.Hashes = []CycloneDXHash{{Algorithm: "SHA-256", Content: d.Sha256}}
+	}
+	for _, l := range d.Licenses {
+		l = strings.TrimSpace(l)
+		license := CycloneDXLicenseChoice{}
+		switch {
+		case spdxExpressionPattern.MatchString(l):
+			license.Expression = l
+		case spdxIDPattern.MatchString(l):
+			license.License = &CycloneDXLicense{ID: l}
+		default:
+			license.License = &CycloneDXLicense{Name: l}
+		}
+		c.Licenses = append(c.Licenses, license)
+	}
+	return c
+}
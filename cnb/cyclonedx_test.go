@@ -0,0 +1,97 @@
+package cnb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+func TestNewCycloneDX(t *testing.T) {
+	ref := "registry.example.com/repo/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	md := ImageMetadata{
+		BuildMetadata: BuildMetadata{
+			Buildpacks: []Buildpack{{ID: "paketo-buildpacks/spring-boot", Version: "1.5.0"}},
+		},
+		Lifecycle: LifecycleMetadata{
+			Stack:    StackMetadata{ID: "io.buildpacks.stacks.bionic"},
+			RunImage: RunImageMetadata{Reference: "index.docker.io/paketobuildpacks/run@sha256:abc"},
+		},
+		Packages: []Dependency{
+			{Name: "libc6", Version: "2.27-3ubuntu1", PURL: "pkg:deb/ubuntu/libc6@2.27-3ubuntu1?arch=amd64", Kind: OSDependency},
+		},
+		Dependencies: []Dependency{
+			{Name: "openjdk-jre", Version: "11.0.7", Kind: RuntimeDependency, Licenses: []string{"GPL-2.0 WITH Classpath-exception-2.0"}},
+			{Name: "spring-core", Version: "5.2.6.RELEASE", Sha256: "c0ffee", Kind: LibraryDependency, Licenses: []string{"Apache-2.0"}},
+			{Name: "spring-core", Version: "5.2.6.RELEASE", Sha256: "c0ffee", Kind: LibraryDependency},
+			{Name: "jaxb-api", Version: "2.3.1", Kind: LibraryDependency, Licenses: []string{"CDDL 1.1"}},
+		},
+	}
+	bom, err := NewCycloneDX(ref, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if bom.Metadata.Component.Name != "registry.example.com/repo/app" || bom.Metadata.Component.Type != "container" {
+		t.Errorf("unexpected metadata component %v", bom.Metadata.Component)
+	}
+	if diff := cmp.Diff([]CycloneDXTool{{Name: "paketo-buildpacks/spring-boot", Version: "1.5.0"}}, bom.Metadata.Tools); diff != "" {
+		t.Errorf("tools (-expected, +actual) = %v", diff)
+	}
+	types := []string{}
+	for _, c := range bom.Components {
+		types = append(types, c.Type+" "+c.Name)
+	}
+	if diff := cmp.Diff([]string{"operating-system io.buildpacks.stacks.bionic", "library libc6", "application openjdk-jre", "library spring-core", "library jaxb-api"}, types); diff != "" {
+		t.Errorf("components (-expected, +actual) = %v", diff)
+	}
+
+	content, err := bom.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the document can be read back as an sbom
+	deps, err := parseCycloneDX(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d := deps[3]; d.Sha256 != "c0ffee" || len(d.Licenses) != 1 || d.Licenses[0] != "Apache-2.0" {
+		t.Errorf("unexpected dependency %v", d)
+	}
+	if d := deps[2]; len(d.Licenses) != 1 || d.Licenses[0] != "GPL-2.0 WITH Classpath-exception-2.0" {
+		t.Errorf("unexpected dependency %v", d)
+	}
+	if d := deps[4]; len(d.Licenses) != 1 || d.Licenses[0] != "CDDL 1.1" {
+		t.Errorf("unexpected dependency %v", d)
+	}
+}
+
+func TestParseStackPackages(t *testing.T) {
+	packages, _ := json.Marshal([]map[string]interface{}{
+		{"name": "libc6", "version": "2.27-3ubuntu1", "arch": "amd64", "source": map[string]string{"name": "glibc", "version": "2.27-3ubuntu1"}},
+	})
+	img, err := mutate.Config(empty.Image, v1.Config{
+		Labels: map[string]string{
+			"io.buildpacks.stack.id":   "io.buildpacks.stacks.bionic",
+			"io.paketo.stack.packages": string(packages),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	deps, err := ParseStackPackages(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff([]Dependency{{
+		Name:    "libc6",
+		Version: "2.27-3ubuntu1",
+		PURL:    "pkg:deb/ubuntu/libc6@2.27-3ubuntu1?arch=amd64&upstream=glibc",
+		Kind:    OSDependency,
+	}}, deps); diff != "" {
+		t.Errorf("packages (-expected, +actual) = %v", diff)
+	}
+}
@@ -0,0 +1,129 @@
+package cnb
+
+import (
+	"strings"
+)
+
+// DependencyKind distinguishes libraries packaged with the application from
+// the runtime dependencies contributed by buildpacks.
+type DependencyKind string
+
+const (
+	// LibraryDependency is a library of the application, like a jar.
+	LibraryDependency DependencyKind = "library"
+	// RuntimeDependency is contributed to the image by a buildpack, like a JRE.
+	RuntimeDependency DependencyKind = "runtime"
+	// OSDependency is an operating system package of the stack's run image.
+	OSDependency DependencyKind = "os"
+)
+
+// Dependency is the normalized form of a dependency, independent of the BOM
+// or SBOM format that reported it.
+type Dependency struct {
+	Name      string
+	Version   string
+	Sha256    string
+	PURL      string
+	Licenses  []string
+	Kind      DependencyKind
+	Buildpack Buildpack
+}
+
+// BOMDependencies normalizes the dependencies reported in the BOM of the
+// io.buildpacks.build.metadata label. Entries listing the `dependencies` of
+// an application, as emitted by the Cloud Foundry and Paketo Spring Boot and
+// executable jar buildpacks, contribute a library dependency for each item.
+// Every other entry is a runtime dependency in its own right.
+func BOMDependencies(md BuildMetadata) []Dependency {
+	deps := []Dependency{}
+	for _, entry := range md.BOM {
+		if items, ok := entry.Metadata["dependencies"].([]interface{}); ok {
+			for _, item := range items {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				d := bomDependency(m)
+				d.Kind = LibraryDependency
+				d.Buildpack = entry.Buildpack
+				deps = append(deps, d)
+			}
+			continue
+		}
+		d := bomDependency(entry.Metadata)
+		d.Name = entry.Name
+		if entry.Version != "" {
+			d.Version = entry.Version
+		}
+		d.Kind = RuntimeDependency
+		if purlType(d.PURL) == "maven" {
+			d.Kind = LibraryDependency
+		}
+		d.Buildpack = entry.Buildpack
+		deps = append(deps, d)
+	}
+	return deps
+}
+
+func bomDependency(m map[string]interface{}) Dependency {
+	d := Dependency{}
+	d.Name, _ = m["name"].(string)
+	d.Version, _ = m["version"].(string)
+	d.Sha256, _ = m["sha256"].(string)
+	d.PURL, _ = m["purl"].(string)
+	if licenses, ok := m["licenses"].([]interface{}); ok {
+		for _, l := range licenses {
+			switch license := l.(type) {
+			case string:
+				d.Licenses = append(d.Licenses, license)
+			case map[string]interface{}:
+				if t, ok := license["type"].(string); ok && t != "" {
+					d.Licenses = append(d.Licenses, t)
+				}
+			}
+		}
+	}
+	return d
+}
+
+// purlType returns the type of a package URL, like `maven` for
+// `pkg:maven/org.springframework.boot/spring-boot@2.3.0.RELEASE`
+func purlType(purl string) string {
+	if !strings.HasPrefix(purl, "pkg:") {
+		return ""
+	}
+	t := strings.TrimPrefix(purl, "pkg:")
+	if i := strings.Index(t, "/"); i >= 0 {
+		t = t[:i]
+	}
+	return strings.ToLower(t)
+}
+
+// dependencyKindForPURL classifies dependencies reported by an SBOM. Packages
+// from a language ecosystem are libraries, everything else is runtime.
+func dependencyKindForPURL(purl string) DependencyKind {
+	switch purlType(purl) {
+	case "", "generic", "deb", "apk", "rpm":
+		return RuntimeDependency
+	default:
+		return LibraryDependency
+	}
+}
+
+// mergeDependencies removes dependencies that are reported more than once,
+// for example by both the BOM label and an SBOM layer, keeping the first.
+func mergeDependencies(deps ...[]Dependency) []Dependency {
+	merged := []Dependency{}
+	seen := map[string]bool{}
+	for _, ds := range deps {
+		for _, d := range ds {
+			key := strings.Join([]string{string(d.Kind), d.Name, d.Version}, "\x00")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, d)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,124 @@
+package cnb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBOMDependencies_CloudFoundry(t *testing.T) {
+	label, err := ioutil.ReadFile("../samples/petclinic-metadata-build.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var md BuildMetadata
+	if err := json.Unmarshal(label, &md); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	deps := BOMDependencies(md)
+
+	im := ImageMetadata{Dependencies: deps}
+	if diff := cmp.Diff(im.FindDependency("openjdk-jre", RuntimeDependency), &Dependency{
+		Name:     "openjdk-jre",
+		Version:  "11.0.6",
+		Sha256:   "c5a4e69e2be0e3e5f5bb7c759960b20650967d0f571baad4a7f15b2c03bda352",
+		Licenses: []string{"GPL-2.0 WITH Classpath-exception-2.0"},
+		Kind:     RuntimeDependency,
+		Buildpack: Buildpack{
+			ID:      "org.cloudfoundry.openjdk",
+			Version: "v1.2.14",
+		},
+	}); diff != "" {
+		t.Errorf("openjdk-jre (-expected, +actual) = %v", diff)
+	}
+	if diff := cmp.Diff(im.FindDependency("spring-boot", LibraryDependency), &Dependency{
+		Name:    "spring-boot",
+		Version: "2.2.4.RELEASE",
+		Sha256:  "176befc7b90e8498f44e21994a70d69ba360ef1e858ff3cea8282e802372daf2",
+		Kind:    LibraryDependency,
+		Buildpack: Buildpack{
+			ID:      "org.cloudfoundry.springboot",
+			Version: "v1.2.13",
+		},
+	}); diff != "" {
+		t.Errorf("spring-boot (-expected, +actual) = %v", diff)
+	}
+	if d := im.FindDependency("spring-boot", RuntimeDependency); d != nil {
+		t.Errorf("expected the spring-boot BOM entry to only contribute its dependencies, got %v", d)
+	}
+}
+
+func TestBOMDependencies_Paketo(t *testing.T) {
+	md := BuildMetadata{
+		BOM: []BOMEntry{
+			{
+				Name: "jre",
+				Metadata: map[string]interface{}{
+					"name":    "BellSoft Liberica JRE",
+					"version": "11.0.9",
+					"sha256":  "jre-sha",
+					"purl":    "pkg:generic/bellsoft-jre@11.0.9",
+					"licenses": []interface{}{
+						map[string]interface{}{"type": "GPL-2.0 WITH Classpath-exception-2.0"},
+					},
+				},
+				Buildpack: Buildpack{ID: "paketo-buildpacks/bellsoft-liberica", Version: "5.2.0"},
+			},
+			{
+				Name: "dependencies",
+				Metadata: map[string]interface{}{
+					"layer": "application",
+					"dependencies": []interface{}{
+						map[string]interface{}{"name": "spring-boot", "version": "2.3.4.RELEASE", "sha256": "boot-sha"},
+						map[string]interface{}{"name": "spring-web", "version": "5.2.9.RELEASE", "sha256": "web-sha"},
+					},
+				},
+				Buildpack: Buildpack{ID: "paketo-buildpacks/spring-boot", Version: "3.5.0"},
+			},
+			{
+				Name: "spring-boot",
+				Metadata: map[string]interface{}{
+					"classes": "BOOT-INF/classes/",
+					"version": "2.3.4.RELEASE",
+				},
+				Buildpack: Buildpack{ID: "paketo-buildpacks/spring-boot", Version: "3.5.0"},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(BOMDependencies(md), []Dependency{
+		{
+			Name:      "jre",
+			Version:   "11.0.9",
+			Sha256:    "jre-sha",
+			PURL:      "pkg:generic/bellsoft-jre@11.0.9",
+			Licenses:  []string{"GPL-2.0 WITH Classpath-exception-2.0"},
+			Kind:      RuntimeDependency,
+			Buildpack: Buildpack{ID: "paketo-buildpacks/bellsoft-liberica", Version: "5.2.0"},
+		},
+		{
+			Name:      "spring-boot",
+			Version:   "2.3.4.RELEASE",
+			Sha256:    "boot-sha",
+			Kind:      LibraryDependency,
+			Buildpack: Buildpack{ID: "paketo-buildpacks/spring-boot", Version: "3.5.0"},
+		},
+		{
+			Name:      "spring-web",
+			Version:   "5.2.9.RELEASE",
+			Sha256:    "web-sha",
+			Kind:      LibraryDependency,
+			Buildpack: Buildpack{ID: "paketo-buildpacks/spring-boot", Version: "3.5.0"},
+		},
+		{
+			Name:      "spring-boot",
+			Version:   "2.3.4.RELEASE",
+			Kind:      RuntimeDependency,
+			Buildpack: Buildpack{ID: "paketo-buildpacks/spring-boot", Version: "3.5.0"},
+		},
+	}); diff != "" {
+		t.Errorf("dependencies (-expected, +actual) = %v", diff)
+	}
+}
@@ -0,0 +1,146 @@
+package cnb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeSubject is what changed between two images
+type ChangeSubject string
+
+const (
+	DependencyChange ChangeSubject = "dependency"
+	JavaChange       ChangeSubject = "java"
+	BuildpackChange  ChangeSubject = "buildpack"
+)
+
+// Change is an addition, removal or change of the version of a dependency,
+// the Java runtime or a buildpack. The previous version is empty for
+// additions, the latest version is empty for removals.
+type Change struct {
+	Subject  ChangeSubject
+	Name     string
+	Previous string
+	Latest   string
+}
+
+func (c Change) Added() bool {
+	return c.Previous == "" && c.Latest != ""
+}
+
+func (c Change) Removed() bool {
+	return c.Previous != "" && c.Latest == ""
+}
+
+// String describes the change, like `spring-boot 2.2.5 → 2.3.0` or
+// `+spring-boot-starter-actuator`
+func (c Change) String() string {
+	name := c.Name
+	if c.Subject == BuildpackChange {
+		name = "buildpack " + name
+	}
+	switch {
+	case c.Added():
+		return "+" + name
+	case c.Removed():
+		return "-" + name
+	}
+	return fmt.Sprintf("%s %s → %s", name, c.Previous, c.Latest)
+}
+
+// ImageMetadataDiff lists the changes between two images
+type ImageMetadataDiff []Change
+
+// Filter returns the changes of the subject
+func (d ImageMetadataDiff) Filter(subject ChangeSubject) ImageMetadataDiff {
+	changes := ImageMetadataDiff{}
+	for _, c := range d {
+		if c.Subject == subject {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Summarize describes the changes, naming at most max changes when max is
+// positive
+func (d ImageMetadataDiff) Summarize(max int) string {
+	changes := []string{}
+	for _, c := range d {
+		changes = append(changes, c.String())
+	}
+	if max > 0 && len(changes) > max {
+		return fmt.Sprintf("%s, and %d more", strings.Join(changes[:max], ", "), len(changes)-max)
+	}
+	return strings.Join(changes, ", ")
+}
+
+// DiffImageMetadata compares the Java runtime, dependencies and buildpacks of
+// two images. Changes are ordered by subject, then by name.
+func DiffImageMetadata(previous, latest ImageMetadata) ImageMetadataDiff {
+	diff := ImageMetadataDiff{}
+
+	previousJava, latestJava := "", ""
+	if jre := previous.JavaRuntime(); jre != nil {
+		previousJava = jre.Version
+	}
+	if jre := latest.JavaRuntime(); jre != nil {
+		latestJava = jre.Version
+	}
+	if previousJava != latestJava {
+		diff = append(diff, Change{Subject: JavaChange, Name: "java", Previous: previousJava, Latest: latestJava})
+	}
+
+	// the java runtime is reported on its own
+	diff = append(diff, diffVersions(DependencyChange, dependencyVersions(previous), dependencyVersions(latest))...)
+
+	previousBuildpacks, latestBuildpacks := map[string]string{}, map[string]string{}
+	for _, bp := range previous.Buildpacks {
+		previousBuildpacks[bp.ID] = bp.Version
+	}
+	for _, bp := range latest.Buildpacks {
+		latestBuildpacks[bp.ID] = bp.Version
+	}
+	diff = append(diff, diffVersions(BuildpackChange, previousBuildpacks, latestBuildpacks)...)
+
+	return diff
+}
+
+// dependencyVersions maps the names of the dependencies, other than the Java
+// runtime, to their versions
+func dependencyVersions(md ImageMetadata) map[string]string {
+	versions := map[string]string{}
+	jre := md.JavaRuntime()
+	for _, d := range md.Dependencies {
+		if jre != nil && d.Name == jre.Name && d.Kind == jre.Kind {
+			continue
+		}
+		if d.Version == "" {
+			// dependencies without a version are still present
+			d.Version = "unknown"
+		}
+		versions[d.Name] = d.Version
+	}
+	return versions
+}
+
+func diffVersions(subject ChangeSubject, previous, latest map[string]string) []Change {
+	names := []string{}
+	for name := range previous {
+		names = append(names, name)
+	}
+	for name := range latest {
+		if _, ok := previous[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	changes := []Change{}
+	for _, name := range names {
+		if previous[name] != latest[name] {
+			changes = append(changes, Change{Subject: subject, Name: name, Previous: previous[name], Latest: latest[name]})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,49 @@
+package cnb
+
+import (
+	"testing"
+)
+
+func TestDiffImageMetadata(t *testing.T) {
+	previous := ImageMetadata{
+		BuildMetadata: BuildMetadata{
+			Buildpacks: []Buildpack{
+				{ID: "paketo-buildpacks/bellsoft-liberica", Version: "2.1.0"},
+				{ID: "paketo-buildpacks/executable-jar", Version: "1.2.0"},
+			},
+		},
+		Dependencies: []Dependency{
+			{Name: "openjdk-jre", Version: "11.0.6", Kind: RuntimeDependency},
+			{Name: "spring-boot", Version: "2.2.5", Kind: LibraryDependency},
+			{Name: "spring-web", Version: "5.2.4", Kind: LibraryDependency},
+			{Name: "jackson-core", Version: "2.10.2", Kind: LibraryDependency},
+		},
+	}
+	latest := ImageMetadata{
+		BuildMetadata: BuildMetadata{
+			Buildpacks: []Buildpack{
+				{ID: "paketo-buildpacks/bellsoft-liberica", Version: "2.2.0"},
+				{ID: "paketo-buildpacks/spring-boot", Version: "1.5.0"},
+			},
+		},
+		Dependencies: []Dependency{
+			{Name: "openjdk-jre", Version: "11.0.7", Kind: RuntimeDependency},
+			{Name: "spring-boot", Version: "2.3.0", Kind: LibraryDependency},
+			{Name: "spring-boot-starter-actuator", Version: "2.3.0", Kind: LibraryDependency},
+			{Name: "jackson-core", Version: "2.10.2", Kind: LibraryDependency},
+		},
+	}
+
+	diff := DiffImageMetadata(previous, latest)
+	expected := "java 11.0.6 → 11.0.7, spring-boot 2.2.5 → 2.3.0, +spring-boot-starter-actuator, -spring-web, " +
+		"buildpack paketo-buildpacks/bellsoft-liberica 2.1.0 → 2.2.0, -buildpack paketo-buildpacks/executable-jar, +buildpack paketo-buildpacks/spring-boot"
+	if actual := diff.Summarize(0); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if actual := diff.Filter(DependencyChange).Summarize(2); actual != "spring-boot 2.2.5 → 2.3.0, +spring-boot-starter-actuator, and 1 more" {
+		t.Errorf("unexpected summary %q", actual)
+	}
+	if len(DiffImageMetadata(latest, latest)) != 0 {
+		t.Errorf("expected no changes for the same image")
+	}
+}
@@ -0,0 +1,495 @@
+package cnb
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// MetadataSource describes how the metadata for an image was detected
+type MetadataSource string
+
+const (
+	// BuildpackMetadataSource metadata is recorded on the image by the
+	// buildpack lifecycle
+	BuildpackMetadataSource MetadataSource = "buildpack"
+	// JarMetadataSource metadata is detected by inspecting the jars within the
+	// image's layers
+	JarMetadataSource MetadataSource = "jar"
+	// NoMetadataSource no metadata was found for the image
+	NoMetadataSource MetadataSource = "none"
+)
+
+// maxJarSize limits the size of archives, and of the entries of archives,
+// read into memory while looking for a Spring Boot fat jar
+var maxJarSize int64 = 256 * 1024 * 1024
+
+// maxScanSize limits the bytes scanned for an image, counting the uncompressed
+// layers along with the entries read from the archives within them
+var maxScanSize int64 = 2 * 1024 * 1024 * 1024
+
+// maxMetadataFileSize limits the manifests and pom.properties read from the
+// libraries of an application
+const maxMetadataFileSize = 1024 * 1024
+
+var (
+	jarVersionPattern = regexp.MustCompile(`^(.+?)-([0-9].*)$`)
+
+	pomPropertiesPattern = regexp.MustCompile(`^META-INF/maven/[^/]+/[^/]+/pom\.properties$`)
+
+	// directories libraries are exploded into by common image builders
+	libDirs = []string{
+		// Spring Boot exploded and layered jars
+		"BOOT-INF/lib/",
+		// Jib
+		"app/libs/",
+		// Quarkus fast-jar
+		quarkusLibDir,
+	}
+
+	// directories common image builders place applications in
+	appDirs = []string{
+		// buildpacks
+		"workspace/",
+		// Jib
+		"app/",
+		"application/",
+		// Quarkus and other images based on fabric8 or ubi java images
+		"deployments/",
+		"opt/app/",
+		"srv/",
+	}
+)
+
+// quarkusLibDir holds the libraries of a Quarkus fast-jar, named by their
+// group and artifact ids like `io.quarkus.quarkus-core-1.13.0.Final.jar`.
+// Group ids contain dots, so the artifact id is read from the jar's
+// pom.properties.
+const quarkusLibDir = "quarkus-app/lib/main/"
+
+// scanLayers walks the layers looking for a Spring Boot fat jar or the
+// libraries of an exploded jar, along with the application's configuration
+// files. Only the directories applications are placed in are scanned, and
+// files deleted by a whiteout in a later layer are ignored.
+func scanLayers(layers []v1.Layer) (*jarScan, error) {
+	files := map[string]*jarScan{}
+	budget := &scanBudget{remaining: maxScanSize}
+	for _, layer := range layers {
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return nil, err
+		}
+		layerFiles, whiteouts, err := scanLayer(budget.reader(rc), budget)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		// whiteouts only delete the files of lower layers
+		for _, whiteout := range whiteouts {
+			for name := range files {
+				if name == whiteout || strings.HasPrefix(name, whiteout+"/") {
+					delete(files, name)
+				}
+			}
+		}
+		for name, file := range layerFiles {
+			files[name] = file
+		}
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	scan := &jarScan{
+		configFiles: map[string][]byte{},
+	}
+	for _, name := range names {
+		file := files[name]
+		if file.manifest != nil {
+			scan.manifest = file.manifest
+		}
+		scan.libs = append(scan.libs, file.libs...)
+		scan.classCount += file.classCount
+		for configName, content := range file.configFiles {
+			scan.configFiles[configName] = content
+		}
+	}
+	return scan, nil
+}
+
+// scanBudget counts down the bytes left to scan for an image
+type scanBudget struct {
+	remaining int64
+}
+
+// reader counts the bytes read from r against the budget, failing once the
+// budget is spent
+func (b *scanBudget) reader(r io.Reader) io.Reader {
+	return &budgetReader{r: r, budget: b}
+}
+
+// readEntry reads an entry of an archive into memory, returning nil when the
+// entry is larger than maxJarSize. The size an archive declares for a
+// compressed entry can't be trusted, so the entry is read up to the limit.
+func (b *scanBudget) readEntry(r io.Reader) ([]byte, error) {
+	content, err := ioutil.ReadAll(io.LimitReader(b.reader(r), maxJarSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxJarSize {
+		return nil, nil
+	}
+	return content, nil
+}
+
+type budgetReader struct {
+	r      io.Reader
+	budget *scanBudget
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	if r.budget.remaining <= 0 {
+		return 0, fmt.Errorf("image exceeds the %d bytes scanned for application files", maxScanSize)
+	}
+	if int64(len(p)) > r.budget.remaining {
+		p = p[:r.budget.remaining]
+	}
+	n, err := r.r.Read(p)
+	r.budget.remaining -= int64(n)
+	return n, err
+}
+
+type jarScan struct {
+	manifest    map[string]string
+	libs        []map[string]interface{}
+	configFiles map[string][]byte
+	classCount  int
+}
+
+// scanLayer scans each application file in the layer, returning the files
+// by path along with the paths the layer's whiteouts delete. The layer is
+// read through the budget, which also limits the archives within it.
+func scanLayer(r io.Reader, budget *scanBudget) (map[string]*jarScan, []string, error) {
+	files := map[string]*jarScan{}
+	whiteouts := []string{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, whiteouts, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		dir, base := path.Split(name)
+		if strings.HasPrefix(base, ".wh.") {
+			if base == ".wh..wh..opq" {
+				// an opaque directory hides every file of the lower layers
+				whiteouts = append(whiteouts, strings.TrimSuffix(dir, "/"))
+			} else {
+				whiteouts = append(whiteouts, dir+strings.TrimPrefix(base, ".wh."))
+			}
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if !isApplicationFile(name) {
+			continue
+		}
+		file := &jarScan{}
+		switch {
+		case isLib(name) && hdr.Size <= maxJarSize:
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, nil, err
+			}
+			file.libs = []map[string]interface{}{jarDependency(path.Base(name), content)}
+			file.classCount = countClasses(content)
+		case isLib(name):
+			// too large to read, the library is not reported
+			continue
+		case strings.HasSuffix(name, ".class"):
+			file.classCount = 1
+		case applicationConfigPattern.MatchString(name):
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, nil, err
+			}
+			file.configFiles = map[string][]byte{
+				applicationConfigPattern.FindStringSubmatch(name)[1]: content,
+			}
+		case strings.HasSuffix(name, "META-INF/MANIFEST.MF"):
+			manifest := parseManifest(tr)
+			if _, ok := manifest["Spring-Boot-Version"]; !ok {
+				continue
+			}
+			file.manifest = manifest
+		case strings.HasSuffix(name, ".jar") && hdr.Size <= maxJarSize:
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := file.fatJar(content, budget); err != nil {
+				return nil, nil, err
+			}
+		default:
+			continue
+		}
+		files[name] = file
+	}
+}
+
+// fatJar scans the libraries and configuration files of a Spring Boot fat
+// jar. Entries larger than maxJarSize are skipped.
+func (s *jarScan) fatJar(content []byte, budget *scanBudget) error {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		// not a valid archive, ignore
+		return nil
+	}
+	var manifest map[string]string
+	libs := []map[string]interface{}{}
+	configFiles := map[string][]byte{}
+	classCount := 0
+	for _, f := range zr.File {
+		switch {
+		case f.Name == "META-INF/MANIFEST.MF":
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			manifest = parseManifest(io.LimitReader(budget.reader(rc), maxMetadataFileSize))
+			rc.Close()
+		case strings.HasPrefix(f.Name, "BOOT-INF/lib/") && strings.HasSuffix(f.Name, ".jar"):
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			lib, err := budget.readEntry(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if lib == nil {
+				// too large to read, the library is not reported
+				continue
+			}
+			libs = append(libs, jarDependency(path.Base(f.Name), lib))
+			classCount += countClasses(lib)
+		case strings.HasPrefix(f.Name, "BOOT-INF/classes/") && strings.HasSuffix(f.Name, ".class"):
+			classCount++
+		case applicationConfigPattern.MatchString(f.Name):
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			content, err := budget.readEntry(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if content == nil {
+				continue
+			}
+			configFiles[applicationConfigPattern.FindStringSubmatch(f.Name)[1]] = content
+		}
+	}
+	if _, ok := manifest["Spring-Boot-Version"]; !ok {
+		return nil
+	}
+	s.manifest = manifest
+	s.libs = append(s.libs, libs...)
+	s.classCount += classCount
+	s.configFiles = configFiles
+	return nil
+}
+
+// buildMetadata synthesizes BuildMetadata for the jars found. The BOM has a
+// single `spring-boot` entry in the same shape the Spring Boot buildpack
+// contributes, with a dependency for each library jar.
+func (s *jarScan) buildMetadata() BuildMetadata {
+	sort.Slice(s.libs, func(i, j int) bool {
+		return s.libs[i]["name"].(string) < s.libs[j]["name"].(string)
+	})
+	dependencies := make([]interface{}, len(s.libs))
+	for i := range s.libs {
+		dependencies[i] = s.libs[i]
+	}
+	metadata := map[string]interface{}{
+		"dependencies": dependencies,
+	}
+	version := ""
+	if s.manifest != nil {
+		version = s.manifest["Spring-Boot-Version"]
+		metadata["version"] = version
+		metadata["classes"] = s.manifest["Spring-Boot-Classes"]
+		metadata["lib"] = s.manifest["Spring-Boot-Lib"]
+		metadata["start-class"] = s.manifest["Start-Class"]
+	}
+	return BuildMetadata{
+		BOM: []BOMEntry{
+			{
+				Name:     "spring-boot",
+				Version:  version,
+				Metadata: metadata,
+			},
+		},
+	}
+}
+
+// isApplicationFile returns true for files in the directories image builders
+// place applications in, or at the root of the image. The rest of the image,
+// like the JDK, is not part of the application.
+func isApplicationFile(name string) bool {
+	if !strings.Contains(name, "/") {
+		return true
+	}
+	for _, appDir := range appDirs {
+		if strings.HasPrefix(name, appDir) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLib(name string) bool {
+	if !strings.HasSuffix(name, ".jar") {
+		return false
+	}
+	dir, _ := path.Split(name)
+	for _, libDir := range libDirs {
+		if strings.HasSuffix(dir, libDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// jarDependency describes a library from the jar's filename, falling back to
+// the jar's own manifest for the version. Libraries built by Maven or Gradle
+// carry their coordinates in a pom.properties file, which identifies the
+// library by its group and artifact ids in the package URL.
+func jarDependency(filename string, content []byte) map[string]interface{} {
+	sum := sha256.Sum256(content)
+	name := strings.TrimSuffix(filename, ".jar")
+	version := ""
+	if m := jarVersionPattern.FindStringSubmatch(name); m != nil {
+		name, version = m[1], m[2]
+	}
+	var manifest, pom map[string]string
+	if zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content))); err == nil {
+		poms := []map[string]string{}
+		for _, f := range zr.File {
+			if f.Name != "META-INF/MANIFEST.MF" && !pomPropertiesPattern.MatchString(f.Name) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			r := io.LimitReader(rc, maxMetadataFileSize)
+			if f.Name == "META-INF/MANIFEST.MF" {
+				manifest = parseManifest(r)
+			} else {
+				poms = append(poms, parsePropertiesFile(r))
+			}
+			rc.Close()
+		}
+		pom = findPOMProperties(name, poms)
+	}
+	if version == "" {
+		version = manifest["Implementation-Version"]
+	}
+	if version == "" {
+		version = manifest["Bundle-Version"]
+	}
+	if version == "" && pom != nil {
+		version = pom["version"]
+	}
+	if pom != nil {
+		// the filename may be prefixed with the group id
+		name = pom["artifactId"]
+	}
+	lib := map[string]interface{}{
+		"name":    name,
+		"version": version,
+		"sha256":  hex.EncodeToString(sum[:]),
+	}
+	if pom != nil && pom["version"] != "" {
+		lib["purl"] = fmt.Sprintf("pkg:maven/%s/%s@%s", pom["groupId"], pom["artifactId"], pom["version"])
+	}
+	return lib
+}
+
+// findPOMProperties returns the pom.properties describing the library named
+// by the jar's filename. Shaded jars carry the pom.properties of the
+// libraries they embed, which are ignored.
+func findPOMProperties(name string, poms []map[string]string) map[string]string {
+	for _, pom := range poms {
+		groupID, artifactID := pom["groupId"], pom["artifactId"]
+		if groupID == "" || artifactID == "" {
+			continue
+		}
+		if name == artifactID || name == groupID+"."+artifactID {
+			return pom
+		}
+	}
+	return nil
+}
+
+// countClasses counts the classes in a jar
+func countClasses(content []byte) int {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, ".class") {
+			count++
+		}
+	}
+	return count
+}
+
+// parseManifest reads the main attributes of a jar manifest
+func parseManifest(r io.Reader) map[string]string {
+	attributes := map[string]string{}
+	key := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			// end of the main section
+			break
+		}
+		if strings.HasPrefix(line, " ") && key != "" {
+			// continuation of the previous value
+			attributes[key] += line[1:]
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key = line[:i]
+		attributes[key] = strings.TrimSpace(line[i+1:])
+	}
+	return attributes
+}
@@ -0,0 +1,312 @@
+package cnb
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+const testBootManifest = "Manifest-Version: 1.0\r\n" +
+	"Start-Class: com.example.demo.DemoApplication\r\n" +
+	"Spring-Boot-Version: 2.2.5.RELEASE\r\n" +
+	"Spring-Boot-Classes: BOOT-INF/classes/\r\n" +
+	"Spring-Boot-Lib: BOOT-INF/lib/\r\n" +
+	"Main-Class: org.springframework.boot.loader.JarLauncher\r\n\r\n"
+
+func TestParseImageMetadata_FatJar(t *testing.T) {
+	bootJar := testJar(t, map[string]string{
+		"META-INF/MANIFEST.MF":                                    testBootManifest,
+		"BOOT-INF/lib/spring-boot-2.2.5.RELEASE.jar":              "boot",
+		"BOOT-INF/lib/jackson-databind-2.10.2.jar":                "jackson",
+		"BOOT-INF/classes/application.properties":                 "server.port=9000\n",
+		"BOOT-INF/classes/com/example/demo/DemoApplication.class": "",
+		"org/springframework/boot/loader/Launcher.class":          "",
+	})
+	img := testImage(t, map[string]string{
+		"app/demo.jar": bootJar,
+	})
+
+	md, err := ParseImageMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if md.Source != JarMetadataSource {
+		t.Errorf("expected source %q, got %q", JarMetadataSource, md.Source)
+	}
+	if diff := cmp.Diff(md.Dependencies, []Dependency{
+		{Name: "jackson-databind", Version: "2.10.2", Sha256: testSHA256("jackson"), Kind: LibraryDependency},
+		{Name: "spring-boot", Version: "2.2.5.RELEASE", Sha256: testSHA256("boot"), Kind: LibraryDependency},
+	}); diff != "" {
+		t.Errorf("dependencies (-expected, +actual) = %v", diff)
+	}
+	boot := md.FindBOM("spring-boot")
+	if boot.Version != "2.2.5.RELEASE" {
+		t.Errorf("expected version %q, got %q", "2.2.5.RELEASE", boot.Version)
+	}
+	if startClass := boot.Metadata["start-class"]; startClass != "com.example.demo.DemoApplication" {
+		t.Errorf("expected start-class %q, got %q", "com.example.demo.DemoApplication", startClass)
+	}
+	if port := md.ApplicationProperties.Resolve(nil)["server.port"]; port != "9000" {
+		t.Errorf("expected server.port %q, got %q", "9000", port)
+	}
+	// loader classes are not part of the application
+	if md.ClassCount != 1 {
+		t.Errorf("expected 1 class, got %d", md.ClassCount)
+	}
+}
+
+func TestParseImageMetadata_ExplodedJar(t *testing.T) {
+	// a layered jar extracted into separate image layers
+	dependencies := sbomLayer(t, map[string]string{
+		"workspace/BOOT-INF/lib/spring-boot-2.3.0.RELEASE.jar": "boot",
+		// the version is read from the jar's manifest when missing from the filename
+		"workspace/BOOT-INF/lib/unversioned.jar": testJar(t, map[string]string{
+			"META-INF/MANIFEST.MF":  "Manifest-Version: 1.0\nImplementation-Version: 1.2.3\n",
+			"com/example/Lib.class": "",
+		}),
+	})
+	application := sbomLayer(t, map[string]string{
+		"workspace/META-INF/MANIFEST.MF":                               testBootManifest,
+		"workspace/BOOT-INF/classes/com/example/DemoApplication.class": "",
+	})
+	img, err := mutate.AppendLayers(empty.Image, dependencies, application)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	md, err := ParseImageMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if md.Source != JarMetadataSource {
+		t.Errorf("expected source %q, got %q", JarMetadataSource, md.Source)
+	}
+	names := []string{}
+	for _, d := range md.Dependencies {
+		names = append(names, d.Name+"@"+d.Version)
+	}
+	if diff := cmp.Diff(names, []string{"spring-boot@2.3.0.RELEASE", "unversioned@1.2.3"}); diff != "" {
+		t.Errorf("dependencies (-expected, +actual) = %v", diff)
+	}
+	if md.ClassCount != 2 {
+		t.Errorf("expected 2 classes, got %d", md.ClassCount)
+	}
+}
+
+func TestParseImageMetadata_Whiteouts(t *testing.T) {
+	base := sbomLayer(t, map[string]string{
+		"workspace/META-INF/MANIFEST.MF":              testBootManifest,
+		"workspace/BOOT-INF/lib/deleted-1.0.0.jar":    "deleted",
+		"workspace/BOOT-INF/lib/kept-1.0.0.jar":       "kept",
+		"workspace/old/BOOT-INF/lib/hidden-1.0.0.jar": "hidden",
+		// outside of the application's directories
+		"usr/lib/jvm/BOOT-INF/lib/jdk-11.0.0.jar": "jdk",
+	})
+	changes := sbomLayer(t, map[string]string{
+		"workspace/BOOT-INF/lib/.wh.deleted-1.0.0.jar": "",
+		"workspace/old/.wh..wh..opq":                   "",
+		"workspace/old/BOOT-INF/lib/added-1.0.0.jar":   "added",
+	})
+	img, err := mutate.AppendLayers(empty.Image, base, changes)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	md, err := ParseImageMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	names := []string{}
+	for _, d := range md.Dependencies {
+		names = append(names, d.Name)
+	}
+	if diff := cmp.Diff(names, []string{"added", "kept"}); diff != "" {
+		t.Errorf("dependencies (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestParseImageMetadata_POMProperties(t *testing.T) {
+	beans := testJar(t, map[string]string{
+		"META-INF/maven/org.springframework/spring-beans/pom.properties": "groupId=org.springframework\nartifactId=spring-beans\nversion=5.3.17\n",
+	})
+	shaded := testJar(t, map[string]string{
+		// the coordinates of an embedded library don't describe the jar
+		"META-INF/maven/com.google.guava/guava/pom.properties": "groupId=com.google.guava\nartifactId=guava\nversion=30.1-jre\n",
+	})
+	img := testImage(t, map[string]string{
+		"workspace/META-INF/MANIFEST.MF":                 testBootManifest,
+		"workspace/BOOT-INF/lib/spring-beans-5.3.17.jar": beans,
+		"workspace/BOOT-INF/lib/shaded-1.0.0.jar":        shaded,
+	})
+
+	md, err := ParseImageMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(md.Dependencies, []Dependency{
+		{Name: "shaded", Version: "1.0.0", Sha256: testSHA256(shaded), Kind: LibraryDependency},
+		{Name: "spring-beans", Version: "5.3.17", Sha256: testSHA256(beans), PURL: "pkg:maven/org.springframework/spring-beans@5.3.17", Kind: LibraryDependency},
+	}); diff != "" {
+		t.Errorf("dependencies (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestParseImageMetadata_QuarkusFastJar(t *testing.T) {
+	pom := func(groupID, artifactID, version string) string {
+		return testJar(t, map[string]string{
+			"META-INF/maven/" + groupID + "/" + artifactID + "/pom.properties": "groupId=" + groupID + "\nartifactId=" + artifactID + "\nversion=" + version + "\n",
+		})
+	}
+	img := testImage(t, map[string]string{
+		"deployments/quarkus-app/quarkus-run.jar":                                    "run",
+		"deployments/quarkus-app/lib/main/io.quarkus.quarkus-core-1.13.0.Final.jar":  pom("io.quarkus", "quarkus-core", "1.13.0.Final"),
+		"deployments/quarkus-app/lib/main/org.postgresql.postgresql-42.2.19.jar":     pom("org.postgresql", "postgresql", "42.2.19"),
+		"deployments/quarkus-app/lib/main/jakarta.ws.rs.jakarta.ws.rs-api-2.1.6.jar": pom("jakarta.ws.rs", "jakarta.ws.rs-api", "2.1.6"),
+		"deployments/quarkus-app/lib/main/com.example.without-coordinates-1.0.0.jar": "unknown",
+	})
+
+	md, err := ParseImageMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	names := []string{}
+	for _, d := range md.Dependencies {
+		names = append(names, d.Name+"@"+d.Version)
+	}
+	// libraries are named by their artifact id, without the group id, when
+	// the jar has a pom.properties
+	if diff := cmp.Diff(names, []string{
+		"com.example.without-coordinates@1.0.0",
+		"jakarta.ws.rs-api@2.1.6",
+		"postgresql@42.2.19",
+		"quarkus-core@1.13.0.Final",
+	}); diff != "" {
+		t.Errorf("dependencies (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestParseImageMetadata_OversizedEntry(t *testing.T) {
+	defer func(size int64) { maxJarSize = size }(maxJarSize)
+	maxJarSize = 4 * 1024
+
+	// compresses to a fraction of the limit
+	bomb := strings.Repeat("0", int(maxJarSize)+1)
+	bootJar := testJar(t, map[string]string{
+		"META-INF/MANIFEST.MF":                       testBootManifest,
+		"BOOT-INF/lib/spring-boot-2.2.5.RELEASE.jar": "boot",
+		"BOOT-INF/lib/bomb-1.0.0.jar":                bomb,
+		"BOOT-INF/classes/application.properties":    bomb,
+	})
+	if int64(len(bootJar)) > maxJarSize {
+		t.Fatalf("expected the jar to be smaller than %d bytes, was %d", maxJarSize, len(bootJar))
+	}
+	img := testImage(t, map[string]string{
+		"app/demo.jar": bootJar,
+	})
+
+	md, err := ParseImageMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	names := []string{}
+	for _, d := range md.Dependencies {
+		names = append(names, d.Name)
+	}
+	if diff := cmp.Diff(names, []string{"spring-boot"}); diff != "" {
+		t.Errorf("dependencies (-expected, +actual) = %v", diff)
+	}
+	if properties := md.ApplicationProperties.Resolve(nil); len(properties) != 0 {
+		t.Errorf("expected no application properties, got %v", properties)
+	}
+}
+
+func TestParseImageMetadata_ScanLimit(t *testing.T) {
+	defer func(size int64) { maxScanSize = size }(maxScanSize)
+	maxScanSize = 16 * 1024
+
+	img := testImage(t, map[string]string{
+		"workspace/META-INF/MANIFEST.MF":                 testBootManifest,
+		"workspace/BOOT-INF/lib/spring-boot-2.3.0.jar":   "boot",
+		"workspace/BOOT-INF/classes/application.yml":     strings.Repeat("#", int(maxScanSize)),
+		"workspace/BOOT-INF/classes/com/example/A.class": "",
+	})
+
+	if _, err := ParseImageMetadata(img); err == nil {
+		t.Errorf("expected error for an image larger than the scan limit")
+	}
+}
+
+func TestParseImageMetadata_NoMetadata(t *testing.T) {
+	img := testImage(t, map[string]string{
+		"usr/lib/jvm/lib/jrt-fs.jar": testJar(t, map[string]string{
+			"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\n",
+		}),
+	})
+
+	md, err := ParseImageMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if md.Source != NoMetadataSource {
+		t.Errorf("expected source %q, got %q", NoMetadataSource, md.Source)
+	}
+	if len(md.Dependencies) != 0 {
+		t.Errorf("expected no dependencies, got %v", md.Dependencies)
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	manifest := parseManifest(bytes.NewBufferString("Manifest-Version: 1.0\r\n" +
+		"Implementation-Title: a-very-long-title-that-is-wrapped-across-seve\r\n" +
+		" ral-lines\r\n" +
+		"\r\n" +
+		"Name: ignored/\r\n" +
+		"Implementation-Title: ignored\r\n"))
+	if diff := cmp.Diff(manifest, map[string]string{
+		"Manifest-Version":     "1.0",
+		"Implementation-Title": "a-very-long-title-that-is-wrapped-across-several-lines",
+	}); diff != "" {
+		t.Errorf("manifest (-expected, +actual) = %v", diff)
+	}
+}
+
+func testImage(t *testing.T, files map[string]string) v1.Image {
+	t.Helper()
+	img, err := mutate.AppendLayers(empty.Image, sbomLayer(t, files))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return img
+}
+
+func testJar(t *testing.T, files map[string]string) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return buf.String()
+}
+
+func testSHA256(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
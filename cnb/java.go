@@ -0,0 +1,58 @@
+package cnb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// names of the BOM entries buildpacks contribute for the Java runtime
+var javaRuntimeNames = []string{"openjdk-jre", "openjdk-jdk", "jre", "jdk"}
+
+// JavaRuntime returns the JRE or JDK contributed by a buildpack, or nil
+func (m *ImageMetadata) JavaRuntime() *Dependency {
+	for _, name := range javaRuntimeNames {
+		if d := m.FindDependency(name, RuntimeDependency); d != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+// JavaVersion is a Java runtime version. The update is the patch level of
+// the major version, like 242 for both `1.8.0_242` and `8.0.242`.
+type JavaVersion struct {
+	Major  int
+	Update int
+}
+
+var (
+	legacyJavaVersionPattern = regexp.MustCompile(`^1\.([0-9]+)\.[0-9]+(?:_([0-9]+))?`)
+	javaVersionPattern       = regexp.MustCompile(`^([0-9]+)(?:\.[0-9]+(?:\.([0-9]+))?)?`)
+)
+
+// ParseJavaVersion parses the version formats used by JRE distributions
+func ParseJavaVersion(version string) (JavaVersion, error) {
+	m := legacyJavaVersionPattern.FindStringSubmatch(version)
+	if m == nil {
+		m = javaVersionPattern.FindStringSubmatch(version)
+	}
+	if m == nil {
+		return JavaVersion{}, fmt.Errorf("invalid java version %q", version)
+	}
+	v := JavaVersion{}
+	v.Major, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		v.Update, _ = strconv.Atoi(m[2])
+	}
+	return v, nil
+}
+
+// AtLeast returns true if the version is the major version at the update
+// or later, or a later major version
+func (v JavaVersion) AtLeast(major, update int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Update >= update
+}
@@ -0,0 +1,59 @@
+package cnb
+
+import (
+	"testing"
+)
+
+func TestParseJavaVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected JavaVersion
+	}{
+		{version: "1.8.0_242", expected: JavaVersion{Major: 8, Update: 242}},
+		{version: "1.8.0", expected: JavaVersion{Major: 8}},
+		{version: "8.0.242", expected: JavaVersion{Major: 8, Update: 242}},
+		{version: "11.0.6", expected: JavaVersion{Major: 11, Update: 6}},
+		{version: "11.0.6+10", expected: JavaVersion{Major: 11, Update: 6}},
+		{version: "17", expected: JavaVersion{Major: 17}},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			v, err := ParseJavaVersion(test.version)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if v != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, v)
+			}
+		})
+	}
+
+	if _, err := ParseJavaVersion("latest"); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestJavaVersion_AtLeast(t *testing.T) {
+	v := JavaVersion{Major: 8, Update: 191}
+	if !v.AtLeast(8, 191) || !v.AtLeast(8, 131) || !v.AtLeast(7, 300) {
+		t.Errorf("expected %v to be at least 8u191, 8u131 and 7u300", v)
+	}
+	if v.AtLeast(8, 192) || v.AtLeast(10, 0) {
+		t.Errorf("expected %v to be less than 8u192 and 10", v)
+	}
+}
+
+func TestImageMetadata_JavaRuntime(t *testing.T) {
+	md := ImageMetadata{
+		Dependencies: []Dependency{
+			{Name: "jre", Version: "1.2.3", Kind: LibraryDependency},
+			{Name: "openjdk-jre", Version: "11.0.6", Kind: RuntimeDependency},
+		},
+	}
+	if jre := md.JavaRuntime(); jre == nil || jre.Version != "11.0.6" {
+		t.Errorf("expected openjdk-jre 11.0.6, got %v", jre)
+	}
+	if jre := (&ImageMetadata{}).JavaRuntime(); jre != nil {
+		t.Errorf("expected no java runtime, got %v", jre)
+	}
+}
@@ -0,0 +1,95 @@
+package cnb
+
+import (
+	"strings"
+)
+
+// LicensePolicy allows and denies licenses by their SPDX identifiers.
+// Identifiers are matched case-insensitively. When Allow is empty, every
+// license that is not denied is permitted.
+type LicensePolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// Permits returns true if the SPDX license expression is permitted. For
+// `OR` expressions one of the licenses must be permitted, for `AND`
+// expressions every license must be permitted. A license with an exception,
+// like `GPL-2.0 WITH Classpath-exception-2.0`, is matched as a whole before
+// falling back to the license without the exception.
+func (p LicensePolicy) Permits(expression string) bool {
+	tokens := tokenizeLicenseExpression(expression)
+	if len(tokens) == 0 {
+		return false
+	}
+	permitted, _ := p.permitsOr(tokens)
+	return permitted
+}
+
+func (p LicensePolicy) permitsOr(tokens []string) (bool, []string) {
+	permitted, tokens := p.permitsAnd(tokens)
+	for len(tokens) > 0 && strings.EqualFold(tokens[0], "OR") {
+		var next bool
+		next, tokens = p.permitsAnd(tokens[1:])
+		permitted = permitted || next
+	}
+	return permitted, tokens
+}
+
+func (p LicensePolicy) permitsAnd(tokens []string) (bool, []string) {
+	permitted, tokens := p.permitsTerm(tokens)
+	for len(tokens) > 0 && strings.EqualFold(tokens[0], "AND") {
+		var next bool
+		next, tokens = p.permitsTerm(tokens[1:])
+		permitted = permitted && next
+	}
+	return permitted, tokens
+}
+
+func (p LicensePolicy) permitsTerm(tokens []string) (bool, []string) {
+	if len(tokens) == 0 {
+		return false, tokens
+	}
+	if tokens[0] == "(" {
+		permitted, rest := p.permitsOr(tokens[1:])
+		if len(rest) > 0 && rest[0] == ")" {
+			rest = rest[1:]
+		}
+		return permitted, rest
+	}
+	license, tokens := tokens[0], tokens[1:]
+	if len(tokens) > 1 && strings.EqualFold(tokens[0], "WITH") {
+		withException := license + " WITH " + tokens[1]
+		tokens = tokens[2:]
+		if listsLicense(p.Allow, withException) {
+			return true, tokens
+		}
+		if listsLicense(p.Deny, withException) {
+			return false, tokens
+		}
+	}
+	return p.permitsLicense(license), tokens
+}
+
+func (p LicensePolicy) permitsLicense(license string) bool {
+	if listsLicense(p.Deny, license) {
+		return false
+	}
+	return len(p.Allow) == 0 || listsLicense(p.Allow, license)
+}
+
+func listsLicense(licenses []string, license string) bool {
+	normalized := strings.Join(strings.Fields(license), " ")
+	for _, l := range licenses {
+		if strings.EqualFold(strings.Join(strings.Fields(l), " "), normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenizeLicenseExpression(expression string) []string {
+	expression = strings.ReplaceAll(expression, "(", " ( ")
+	expression = strings.ReplaceAll(expression, ")", " ) ")
+	return strings.Fields(expression)
+}
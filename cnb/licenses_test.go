@@ -0,0 +1,48 @@
+package cnb
+
+import (
+	"testing"
+)
+
+func TestLicensePolicy_Permits(t *testing.T) {
+	policy := LicensePolicy{
+		Allow: []string{"Apache-2.0", "MIT", "GPL-2.0 WITH Classpath-exception-2.0", "EPL-2.0"},
+		Deny:  []string{"GPL-2.0", "AGPL-3.0"},
+	}
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{expression: "Apache-2.0", expected: true},
+		{expression: "apache-2.0", expected: true},
+		{expression: "BSD-3-Clause", expected: false},
+		{expression: "GPL-2.0", expected: false},
+		{expression: "GPL-2.0 WITH Classpath-exception-2.0", expected: true},
+		{expression: "AGPL-3.0 WITH Classpath-exception-2.0", expected: false},
+		{expression: "MIT OR GPL-2.0", expected: true},
+		{expression: "MIT AND GPL-2.0", expected: false},
+		{expression: "(EPL-2.0 OR GPL-2.0) AND Apache-2.0", expected: true},
+		{expression: "EPL-2.0 OR GPL-2.0 AND Apache-2.0", expected: true},
+		{expression: "(AGPL-3.0 OR GPL-2.0) AND Apache-2.0", expected: false},
+		{expression: "", expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			if actual := policy.Permits(test.expression); actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLicensePolicy_Permits_DenyOnly(t *testing.T) {
+	policy := LicensePolicy{
+		Deny: []string{"GPL-3.0"},
+	}
+	if !policy.Permits("BSD-3-Clause") {
+		t.Errorf("expected licenses that are not denied to be permitted")
+	}
+	if policy.Permits("GPL-3.0") {
+		t.Errorf("expected denied license not to be permitted")
+	}
+}
@@ -0,0 +1,92 @@
+package cnb
+
+import (
+	"encoding/json"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	lifecycleMetadataLabel = "io.buildpacks.lifecycle.metadata"
+	stackIDLabel           = "io.buildpacks.stack.id"
+	platformAPIEnv         = "CNB_PLATFORM_API"
+)
+
+type LifecycleMetadata struct {
+	App        []LayerMetadata           `json:"app"`
+	Config     LayerMetadata             `json:"config"`
+	Launcher   LayerMetadata             `json:"launcher"`
+	Buildpacks []BuildpackLayersMetadata `json:"buildpacks"`
+	RunImage   RunImageMetadata          `json:"runImage"`
+	Stack      StackMetadata             `json:"stack"`
+	SBOM       *LayerMetadata            `json:"sbom,omitempty"`
+	// PlatformAPI the image was exported with, read from the image's
+	// CNB_PLATFORM_API environment variable rather than the lifecycle
+	// metadata
+	PlatformAPI string `json:"-"`
+}
+
+type LayerMetadata struct {
+	SHA string `json:"sha"`
+}
+
+type BuildpackLayersMetadata struct {
+	ID      string                            `json:"key"`
+	Version string                            `json:"version"`
+	Layers  map[string]BuildpackLayerMetadata `json:"layers"`
+}
+
+type BuildpackLayerMetadata struct {
+	SHA    string                 `json:"sha"`
+	Data   map[string]interface{} `json:"data"`
+	Build  bool                   `json:"build"`
+	Launch bool                   `json:"launch"`
+	Cache  bool                   `json:"cache"`
+}
+
+type RunImageMetadata struct {
+	TopLayer  string `json:"topLayer"`
+	Reference string `json:"reference"`
+}
+
+type StackMetadata struct {
+	// ID is read from the io.buildpacks.stack.id label rather than the
+	// lifecycle metadata
+	ID       string                `json:"-"`
+	RunImage StackRunImageMetadata `json:"runImage"`
+}
+
+type StackRunImageMetadata struct {
+	Image   string   `json:"image"`
+	Mirrors []string `json:"mirrors,omitempty"`
+}
+
+func (m *LifecycleMetadata) FindBuildpack(id string) BuildpackLayersMetadata {
+	for _, bp := range m.Buildpacks {
+		if bp.ID == id {
+			return bp
+		}
+	}
+	return BuildpackLayersMetadata{}
+}
+
+func ParseLifecycleMetadata(img v1.Image) (LifecycleMetadata, error) {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return LifecycleMetadata{}, err
+	}
+	var md LifecycleMetadata
+	if label, ok := cfg.Config.Labels[lifecycleMetadataLabel]; ok {
+		if err := json.Unmarshal([]byte(label), &md); err != nil {
+			return LifecycleMetadata{}, err
+		}
+	}
+	md.Stack.ID = cfg.Config.Labels[stackIDLabel]
+	for _, env := range cfg.Config.Env {
+		if strings.HasPrefix(env, platformAPIEnv+"=") {
+			md.PlatformAPI = strings.TrimPrefix(env, platformAPIEnv+"=")
+		}
+	}
+	return md, nil
+}
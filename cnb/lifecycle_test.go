@@ -0,0 +1,93 @@
+package cnb
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/fake"
+)
+
+func TestParseLifecycleMetadata(t *testing.T) {
+	label, err := ioutil.ReadFile("../samples/petclinic-metadata-lifecycle.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	img := &fake.FakeImage{
+		ConfigFileStub: func() (*v1.ConfigFile, error) {
+			return &v1.ConfigFile{
+				Config: v1.Config{
+					Env: []string{"PATH=/cnb/process:/usr/bin", "CNB_PLATFORM_API=0.4"},
+					Labels: map[string]string{
+						"io.buildpacks.lifecycle.metadata": string(label),
+						"io.buildpacks.stack.id":           "io.buildpacks.stacks.bionic",
+					},
+				},
+			}, nil
+		},
+	}
+	md, err := ParseLifecycleMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if diff := cmp.Diff(md.Stack, StackMetadata{
+		ID: "io.buildpacks.stacks.bionic",
+		RunImage: StackRunImageMetadata{
+			Image: "cloudfoundry/run:base-cnb",
+		},
+	}); diff != "" {
+		t.Errorf("stack (-expected, +actual) = %v", diff)
+	}
+
+	if diff := cmp.Diff(md.RunImage, RunImageMetadata{
+		TopLayer:  "sha256:70770ccee3fd0cb3e31eed9008bc5eeb37a12f6965c7faab040fc336771e1fac",
+		Reference: "55c13fb1611064c097725aacd9e456e077bc6533c5d84c296af5ff321c0a662c",
+	}); diff != "" {
+		t.Errorf("run image (-expected, +actual) = %v", diff)
+	}
+
+	if expected, actual := "0.4", md.PlatformAPI; expected != actual {
+		t.Errorf("expected platform API %q, got %q", expected, actual)
+	}
+
+	if expected, actual := 6, len(md.App); expected != actual {
+		t.Errorf("expected %d app layers, got %d", expected, actual)
+	}
+	if expected, actual := "sha256:a9e8a84f59d684926f7dbc105ffc7fc7a39eb87fa25c41526f2fef6ec8220737", md.Launcher.SHA; expected != actual {
+		t.Errorf("expected launcher layer %q, got %q", expected, actual)
+	}
+
+	openjdk := md.FindBuildpack("org.cloudfoundry.openjdk")
+	if expected, actual := "v1.2.14", openjdk.Version; expected != actual {
+		t.Errorf("expected openjdk buildpack version %q, got %q", expected, actual)
+	}
+	if diff := cmp.Diff(openjdk.Layers["class-counter"], BuildpackLayerMetadata{
+		SHA: "sha256:326717d329f7b5ed80925859f3be234d5d792bf004ca0927bb77b85ba8dd8078",
+		Data: map[string]interface{}{
+			"display_name": "Class Counter",
+			"id":           "org.cloudfoundry.openjdk",
+			"name":         "Cloud Foundry OpenJDK Buildpack",
+			"version":      "v1.2.14",
+		},
+		Launch: true,
+	}); diff != "" {
+		t.Errorf("class-counter layer (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestParseLifecycleMetadata_Missing(t *testing.T) {
+	img := &fake.FakeImage{
+		ConfigFileStub: func() (*v1.ConfigFile, error) {
+			return &v1.ConfigFile{}, nil
+		},
+	}
+	md, err := ParseLifecycleMetadata(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(md, LifecycleMetadata{}); diff != "" {
+		t.Errorf("metadata (-expected, +actual) = %v", diff)
+	}
+}
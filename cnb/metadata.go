@@ -57,3 +57,134 @@ func ParseBuildMetadata(img v1.Image) (BuildMetadata, error) {
 	}
 	return md, nil
 }
+
+// ImageMetadata collects the metadata the buildpack lifecycle records on an
+// image
+type ImageMetadata struct {
+	BuildMetadata
+	Lifecycle LifecycleMetadata
+	Project   ProjectMetadata
+	// Dependencies normalized from the BOM and SBOM layer of the image
+	Dependencies []Dependency
+	// Packages of the operating system of the stack's run image
+	Packages []Dependency
+	// ApplicationProperties packaged with the application
+	ApplicationProperties ApplicationProperties
+	// InvalidApplicationProperties are the errors of configuration files
+	// that could not be parsed, keyed by the profile they apply to
+	InvalidApplicationProperties map[string]string
+	// ClassCount is the number of classes packaged with the application,
+	// excluding the classes of the JVM
+	ClassCount int
+	// Source of the metadata
+	Source MetadataSource
+}
+
+// ParseImageMetadata reads the metadata the buildpack lifecycle records on
+// the image, and the configuration files packaged in the image's app layers.
+// Images without the io.buildpacks.build.metadata label fall back to walking
+// the application directories of every layer for the jars and configuration
+// files of a Spring Boot application.
+func ParseImageMetadata(img v1.Image) (ImageMetadata, error) {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	if _, ok := cfg.Config.Labels[buildMetadataLabel]; !ok {
+		return detectImageMetadata(img)
+	}
+	build, err := ParseBuildMetadata(img)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	lifecycle, err := ParseLifecycleMetadata(img)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	project, err := ParseProjectMetadata(img)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	sbom, err := ParseSBOMDependencies(img, lifecycle, build.Buildpacks)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	packages, err := ParseStackPackages(img)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	appLayers := []v1.Layer{}
+	for _, app := range lifecycle.App {
+		diffID, err := v1.NewHash(app.SHA)
+		if err != nil {
+			return ImageMetadata{}, err
+		}
+		layer, err := findLayer(img, diffID)
+		if err != nil {
+			return ImageMetadata{}, err
+		}
+		appLayers = append(appLayers, layer)
+	}
+	scan, err := scanLayers(appLayers)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	properties, invalidProperties, err := parseApplicationProperties(scan.configFiles)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	return ImageMetadata{
+		BuildMetadata:                build,
+		Lifecycle:                    lifecycle,
+		Project:                      project,
+		Dependencies:                 mergeDependencies(BOMDependencies(build), sbom),
+		Packages:                     packages,
+		ApplicationProperties:        properties,
+		InvalidApplicationProperties: invalidProperties,
+		ClassCount:                   scan.classCount,
+		Source:                       BuildpackMetadataSource,
+	}, nil
+}
+
+func detectImageMetadata(img v1.Image) (ImageMetadata, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	scan, err := scanLayers(layers)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	properties, invalidProperties, err := parseApplicationProperties(scan.configFiles)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+	if len(scan.libs) == 0 {
+		return ImageMetadata{
+			Dependencies:                 []Dependency{},
+			ApplicationProperties:        properties,
+			InvalidApplicationProperties: invalidProperties,
+			ClassCount:                   scan.classCount,
+			Source:                       NoMetadataSource,
+		}, nil
+	}
+	build := scan.buildMetadata()
+	return ImageMetadata{
+		BuildMetadata:                build,
+		Dependencies:                 BOMDependencies(build),
+		ApplicationProperties:        properties,
+		InvalidApplicationProperties: invalidProperties,
+		ClassCount:                   scan.classCount,
+		Source:                       JarMetadataSource,
+	}, nil
+}
+
+// FindDependency returns the first dependency with the name and kind, or nil
+func (m *ImageMetadata) FindDependency(name string, kind DependencyKind) *Dependency {
+	for i := range m.Dependencies {
+		if d := &m.Dependencies[i]; d.Name == name && d.Kind == kind {
+			return d
+		}
+	}
+	return nil
+}
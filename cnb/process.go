@@ -0,0 +1,54 @@
+package cnb
+
+// LauncherPath is the location of the buildpack lifecycle launcher in images
+// built by buildpacks. The launcher sets up the environment buildpacks
+// contribute before running a process.
+const LauncherPath = "/cnb/lifecycle/launcher"
+
+// WebProcessType is the process type conventionally used for applications
+// that serve network traffic
+const WebProcessType = "web"
+
+// processTypePlatformAPI is the first platform API whose launcher runs a
+// process given its type
+const processTypePlatformAPI = "0.4"
+
+// FindProcess returns the launch process with the type, or nil
+func (m *BuildMetadata) FindProcess(processType string) *Process {
+	for i := range m.Processes {
+		if p := &m.Processes[i]; p.Type == processType {
+			return p
+		}
+	}
+	return nil
+}
+
+// ProcessTypes lists the types of the image's launch processes
+func (m *BuildMetadata) ProcessTypes() []string {
+	types := []string{}
+	for _, p := range m.Processes {
+		types = append(types, p.Type)
+	}
+	return types
+}
+
+// ContainerCommand is the container command and args that run the process
+// with the launcher. Direct processes are executed as-is, other processes are
+// evaluated by bash with the process's args as positional parameters. A
+// process without a command is launched by its type, when the image's
+// platform API supports it, otherwise nil is returned and the image's
+// entrypoint is left to run.
+func (p *Process) ContainerCommand(platformAPI string) ([]string, []string) {
+	args := append([]string{}, p.Args...)
+	switch {
+	case p.Command == "" && platformAPI != "" && CompareVersions(platformAPI, processTypePlatformAPI) >= 0:
+		return []string{LauncherPath, p.Type}, nil
+	case p.Command == "":
+		return nil, nil
+	case p.Direct:
+		return []string{LauncherPath, "--", p.Command}, args
+	default:
+		// the process type is bash's $0
+		return []string{LauncherPath, "--", "bash", "-c", p.Command + ` "$@"`, p.Type}, args
+	}
+}
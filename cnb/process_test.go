@@ -0,0 +1,75 @@
+package cnb
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProcess_ContainerCommand(t *testing.T) {
+	md := BuildMetadata{
+		Processes: []Process{
+			{Type: "web", Command: "java $JAVA_OPTS -jar app.jar", Args: []string{"--debug"}},
+			{Type: "task", Command: "/bin/task", Args: []string{"run", "all"}, Direct: true},
+			{Type: "worker"},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		processType     string
+		platformAPI     string
+		expectedCommand []string
+		expectedArgs    []string
+	}{{
+		name:            "shell",
+		processType:     "web",
+		expectedCommand: []string{LauncherPath, "--", "bash", "-c", `java $JAVA_OPTS -jar app.jar "$@"`, "web"},
+		expectedArgs:    []string{"--debug"},
+	}, {
+		name:            "direct",
+		processType:     "task",
+		expectedCommand: []string{LauncherPath, "--", "/bin/task"},
+		expectedArgs:    []string{"run", "all"},
+	}, {
+		name:            "commands take precedence over the type",
+		processType:     "task",
+		platformAPI:     "0.6",
+		expectedCommand: []string{LauncherPath, "--", "/bin/task"},
+		expectedArgs:    []string{"run", "all"},
+	}, {
+		name:            "type",
+		processType:     "worker",
+		platformAPI:     "0.4",
+		expectedCommand: []string{LauncherPath, "worker"},
+	}, {
+		name:        "type unsupported by the platform API",
+		processType: "worker",
+		platformAPI: "0.3",
+	}, {
+		name:        "type with an unknown platform API",
+		processType: "worker",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			process := md.FindProcess(test.processType)
+			if process == nil {
+				t.Fatalf("expected process %q", test.processType)
+			}
+			command, args := process.ContainerCommand(test.platformAPI)
+			if diff := cmp.Diff(command, test.expectedCommand); diff != "" {
+				t.Errorf("command (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(args, test.expectedArgs); diff != "" {
+				t.Errorf("args (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+
+	if process := md.FindProcess("missing"); process != nil {
+		t.Errorf("expected no process, got %v", process)
+	}
+	if diff := cmp.Diff(md.ProcessTypes(), []string{"web", "task", "worker"}); diff != "" {
+		t.Errorf("process types (-expected, +actual) = %v", diff)
+	}
+}
@@ -0,0 +1,58 @@
+package cnb
+
+import (
+	"encoding/json"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const projectMetadataLabel = "io.buildpacks.project.metadata"
+
+type ProjectMetadata struct {
+	Source *ProjectSource `json:"source,omitempty"`
+}
+
+type ProjectSource struct {
+	Type     string                 `json:"type"`
+	Version  map[string]interface{} `json:"version"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// Repository returns the location of the source repository, if known
+func (m *ProjectMetadata) Repository() string {
+	if m.Source == nil {
+		return ""
+	}
+	repository, _ := m.Source.Metadata["repository"].(string)
+	return repository
+}
+
+// Revision returns the revision of the source the image was built from, if
+// known. For git sources this is the commit.
+func (m *ProjectMetadata) Revision() string {
+	if m.Source == nil {
+		return ""
+	}
+	for _, key := range []string{"commit", "revision"} {
+		if revision, ok := m.Source.Version[key].(string); ok {
+			return revision
+		}
+	}
+	return ""
+}
+
+func ParseProjectMetadata(img v1.Image) (ProjectMetadata, error) {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return ProjectMetadata{}, err
+	}
+	label, ok := cfg.Config.Labels[projectMetadataLabel]
+	if !ok {
+		return ProjectMetadata{}, nil
+	}
+	var md ProjectMetadata
+	if err := json.Unmarshal([]byte(label), &md); err != nil {
+		return ProjectMetadata{}, err
+	}
+	return md, nil
+}
@@ -0,0 +1,70 @@
+package cnb
+
+import (
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/fake"
+)
+
+func TestParseProjectMetadata(t *testing.T) {
+	tests := []struct {
+		name               string
+		labels             map[string]string
+		expectedType       string
+		expectedRepository string
+		expectedRevision   string
+	}{{
+		name:   "missing",
+		labels: map[string]string{},
+	}, {
+		name: "git",
+		labels: map[string]string{
+			"io.buildpacks.project.metadata": testProjectLabel,
+		},
+		expectedType:       "git",
+		expectedRepository: "https://github.com/spring-projects/spring-petclinic",
+		expectedRevision:   "8a6a2a2ab6ea3e1a6c9e6b2e0d7c8a4c8dd36c89",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := &fake.FakeImage{
+				ConfigFileStub: func() (*v1.ConfigFile, error) {
+					return &v1.ConfigFile{
+						Config: v1.Config{
+							Labels: test.labels,
+						},
+					}, nil
+				},
+			}
+			md, err := ParseProjectMetadata(img)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if test.expectedType != "" && md.Source.Type != test.expectedType {
+				t.Errorf("expected type %q, got %q", test.expectedType, md.Source.Type)
+			}
+			if actual := md.Repository(); actual != test.expectedRepository {
+				t.Errorf("expected repository %q, got %q", test.expectedRepository, actual)
+			}
+			if actual := md.Revision(); actual != test.expectedRevision {
+				t.Errorf("expected revision %q, got %q", test.expectedRevision, actual)
+			}
+		})
+	}
+}
+
+var testProjectLabel = `
+{
+  "source": {
+    "type": "git",
+    "version": {
+      "commit": "8a6a2a2ab6ea3e1a6c9e6b2e0d7c8a4c8dd36c89"
+    },
+    "metadata": {
+      "repository": "https://github.com/spring-projects/spring-petclinic",
+      "refs": ["main"]
+    }
+  }
+}
+`
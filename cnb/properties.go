@@ -0,0 +1,399 @@
+package cnb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ApplicationProperties are the Spring Boot configuration files packaged with
+// an application, flattened and keyed by profile. Properties for the default
+// profile are keyed by the empty string. Documents of a multi-document YAML
+// file are keyed by the profile expression that activates them, like
+// `dev,local` or `cloud & !prod`.
+type ApplicationProperties map[string]map[string]string
+
+// applicationConfigPattern matches Spring Boot configuration files on the
+// application's classpath, capturing the path relative to the classpath root
+var applicationConfigPattern = regexp.MustCompile(`(?:^|/)BOOT-INF/classes/((?:config/)?application(?:-[^/]+)?\.(?:properties|yml|yaml))$`)
+
+// Resolve flattens the properties for the active profiles. Later profiles
+// take precedence over earlier profiles, and every profile takes precedence
+// over the default profile. Properties keyed by a profile expression apply
+// when the expression matches the active profiles, with the precedence of
+// the profile that made it match.
+func (p ApplicationProperties) Resolve(profiles []string) map[string]string {
+	expressions := []string{}
+	for expression := range p {
+		if expression != "" {
+			expressions = append(expressions, expression)
+		}
+	}
+	sort.Strings(expressions)
+	ranks := map[string]int{}
+	for _, expression := range expressions {
+		if !matchProfiles(expression, profiles) {
+			continue
+		}
+		// the first of the active profiles the expression matches
+		for i := 0; i <= len(profiles); i++ {
+			if matchProfiles(expression, profiles[:i]) {
+				ranks[expression] = i
+				break
+			}
+		}
+	}
+	sort.SliceStable(expressions, func(i, j int) bool {
+		return ranks[expressions[i]] < ranks[expressions[j]]
+	})
+
+	resolved := map[string]string{}
+	for key, value := range p[""] {
+		resolved[key] = value
+	}
+	for _, expression := range expressions {
+		if _, ok := ranks[expression]; !ok {
+			continue
+		}
+		for key, value := range p[expression] {
+			resolved[key] = value
+		}
+	}
+	return resolved
+}
+
+// matchProfiles returns true when the profile expression matches the active
+// profiles. An expression is a comma separated list of alternatives, each a
+// profile name or a combination of names with `!`, `&`, `|` and parentheses,
+// as accepted by Spring's `Profiles.of`. Malformed expressions never match.
+func matchProfiles(expression string, profiles []string) bool {
+	active := map[string]bool{}
+	for _, profile := range profiles {
+		active[profile] = true
+	}
+	for _, alternative := range strings.Split(expression, ",") {
+		p := &profileParser{tokens: profileTokens(alternative), active: active}
+		if matched, ok := p.expression(); ok && p.pos == len(p.tokens) && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// profileTokens splits a profile expression into names and operators
+func profileTokens(expression string) []string {
+	tokens := []string{}
+	name := strings.Builder{}
+	flush := func() {
+		if name.Len() != 0 {
+			tokens = append(tokens, name.String())
+			name.Reset()
+		}
+	}
+	for _, r := range expression {
+		switch r {
+		case '!', '&', '|', '(', ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case ' ', '\t':
+			flush()
+		default:
+			name.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type profileParser struct {
+	tokens []string
+	pos    int
+	active map[string]bool
+}
+
+// expression parses operands joined by a single kind of operator, mixing `&`
+// and `|` requires parentheses
+func (p *profileParser) expression() (bool, bool) {
+	matched, ok := p.operand()
+	if !ok {
+		return false, false
+	}
+	operator := ""
+	for p.pos < len(p.tokens) && (p.tokens[p.pos] == "&" || p.tokens[p.pos] == "|") {
+		if operator != "" && operator != p.tokens[p.pos] {
+			return false, false
+		}
+		operator = p.tokens[p.pos]
+		p.pos++
+		next, ok := p.operand()
+		if !ok {
+			return false, false
+		}
+		if operator == "&" {
+			matched = matched && next
+		} else {
+			matched = matched || next
+		}
+	}
+	return matched, true
+}
+
+func (p *profileParser) operand() (bool, bool) {
+	if p.pos == len(p.tokens) {
+		return false, false
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token {
+	case "!":
+		matched, ok := p.operand()
+		return !matched, ok
+	case "(":
+		matched, ok := p.expression()
+		if !ok || p.pos == len(p.tokens) || p.tokens[p.pos] != ")" {
+			return false, false
+		}
+		p.pos++
+		return matched, true
+	case "&", "|", ")":
+		return false, false
+	}
+	return p.active[token], true
+}
+
+// ActiveProfiles splits a `spring.profiles.active` value into profile names
+func ActiveProfiles(value string) []string {
+	profiles := []string{}
+	for _, profile := range strings.Split(value, ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
+
+// parseApplicationProperties parses configuration files keyed by their path
+// relative to the classpath root. Within a profile, files in `config/` take
+// precedence over files at the root, and `.properties` files take precedence
+// over YAML files, matching the order Spring Boot loads them.
+//
+// A YAML file that can't be parsed fails for the default profile, which is
+// always active. Files for other profiles only matter once the profile is
+// activated, so their errors are returned keyed by profile instead.
+func parseApplicationProperties(files map[string][]byte) (ApplicationProperties, map[string]string, error) {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := applicationConfigRank(names[i]), applicationConfigRank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	properties := ApplicationProperties{}
+	invalid := map[string]string{}
+	merge := func(profile string, props map[string]string) {
+		if properties[profile] == nil {
+			properties[profile] = map[string]string{}
+		}
+		for key, value := range props {
+			properties[profile][key] = value
+		}
+	}
+	for _, name := range names {
+		base := path.Base(name)
+		ext := path.Ext(base)
+		profile := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSuffix(base, ext), "application"), "-")
+		if ext == ".properties" {
+			merge(profile, parsePropertiesFile(bytes.NewReader(files[name])))
+			continue
+		}
+		documents, err := parseYAMLFile(bytes.NewReader(files[name]))
+		if err != nil {
+			if profile == "" {
+				return nil, nil, fmt.Errorf("invalid configuration file %s: %s", name, err)
+			}
+			if _, ok := invalid[profile]; !ok {
+				invalid[profile] = fmt.Sprintf("invalid configuration file %s: %s", name, err)
+			}
+			continue
+		}
+		for _, document := range documents {
+			documentProfile := profile
+			// documents within a multi-document file may be activated by profile
+			for _, key := range []string{"spring.config.activate.on-profile", "spring.profiles"} {
+				if on := documentProfiles(document, key); on != "" {
+					documentProfile = on
+					break
+				}
+			}
+			merge(documentProfile, document)
+		}
+	}
+	return properties, invalid, nil
+}
+
+// documentProfiles returns the profile expression of the key, joining the
+// items of a list with commas
+func documentProfiles(document map[string]string, key string) string {
+	if value, ok := document[key]; ok {
+		return strings.TrimSpace(value)
+	}
+	items := []string{}
+	for i := 0; ; i++ {
+		item, ok := document[fmt.Sprintf("%s[%d]", key, i)]
+		if !ok {
+			break
+		}
+		items = append(items, strings.TrimSpace(item))
+	}
+	return strings.Join(items, ",")
+}
+
+func applicationConfigRank(name string) int {
+	rank := 0
+	if strings.HasPrefix(name, "config/") {
+		rank += 10
+	}
+	switch path.Ext(name) {
+	case ".properties":
+		rank += 2
+	case ".yml":
+		rank += 1
+	}
+	return rank
+}
+
+// parsePropertiesFile reads a file in the java.util.Properties format
+func parsePropertiesFile(r io.Reader) map[string]string {
+	properties := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	logical := ""
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical == "" && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+		if continued := strings.TrimSuffix(line, `\`); continued != line && !strings.HasSuffix(continued, `\`) {
+			logical += continued
+			continue
+		}
+		logical += line
+		key, value := splitProperty(logical)
+		properties[unescapeProperty(key)] = unescapeProperty(value)
+		logical = ""
+	}
+	if logical != "" {
+		key, value := splitProperty(logical)
+		properties[unescapeProperty(key)] = unescapeProperty(value)
+	}
+	return properties
+}
+
+// splitProperty splits a logical line at the first unescaped separator
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			value := strings.TrimLeft(line[i:], " \t\f")
+			if value != "" && (value[0] == '=' || value[0] == ':') {
+				value = strings.TrimLeft(value[1:], " \t\f")
+			}
+			return line[:i], value
+		}
+	}
+	return line, ""
+}
+
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// parseYAMLFile reads each document of a YAML file, flattening nested keys
+// into the dotted form used by properties files
+func parseYAMLFile(r io.Reader) ([]map[string]string, error) {
+	documents := []map[string]string{}
+	decoder := yaml.NewDecoder(r)
+	for {
+		var document interface{}
+		if err := decoder.Decode(&document); err == io.EOF {
+			return documents, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if document == nil {
+			continue
+		}
+		properties := map[string]string{}
+		flattenYAML("", document, properties)
+		documents = append(documents, properties)
+	}
+}
+
+func flattenYAML(prefix string, value interface{}, properties map[string]string) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		for key, child := range v {
+			name := fmt.Sprint(key)
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			flattenYAML(name, child, properties)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenYAML(fmt.Sprintf("%s[%d]", prefix, i), child, properties)
+		}
+	case nil:
+		properties[prefix] = ""
+	default:
+		properties[prefix] = fmt.Sprint(v)
+	}
+}
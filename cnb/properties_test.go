@@ -0,0 +1,216 @@
+package cnb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseApplicationProperties(t *testing.T) {
+	properties, invalid, err := parseApplicationProperties(map[string][]byte{
+		"application.yml": []byte(`
+server:
+  port: 9000
+management:
+  endpoints:
+    web:
+      base-path: /manage
+spring:
+  profiles:
+    active: cloud
+---
+spring:
+  profiles: cloud
+server:
+  port: 9443
+`),
+		"application.properties":            []byte("server.port=9001\n"),
+		"config/application.properties":     []byte("greeting=hello\n"),
+		"application-kubernetes.yaml":       []byte("management.server.port: 9090\n"),
+		"application-kubernetes.properties": []byte("management.server.port=9091\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(invalid) != 0 {
+		t.Errorf("unexpected invalid files: %v", invalid)
+	}
+
+	if diff := cmp.Diff(properties.Resolve(nil), map[string]string{
+		// properties files take precedence over yaml files
+		"server.port":                        "9001",
+		"management.endpoints.web.base-path": "/manage",
+		"spring.profiles.active":             "cloud",
+		"greeting":                           "hello",
+	}); diff != "" {
+		t.Errorf("default profile (-expected, +actual) = %v", diff)
+	}
+	if diff := cmp.Diff(properties.Resolve(ActiveProfiles("cloud, kubernetes")), map[string]string{
+		"server.port":                        "9443",
+		"management.endpoints.web.base-path": "/manage",
+		"management.server.port":             "9091",
+		"spring.profiles.active":             "cloud",
+		"spring.profiles":                    "cloud",
+		"greeting":                           "hello",
+	}); diff != "" {
+		t.Errorf("active profiles (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestParseApplicationProperties_InvalidYAML(t *testing.T) {
+	_, _, err := parseApplicationProperties(map[string][]byte{
+		"application.yml": []byte("server: [\n"),
+	})
+	if err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestParseApplicationProperties_InvalidProfileYAML(t *testing.T) {
+	properties, invalid, err := parseApplicationProperties(map[string][]byte{
+		"application.yml":       []byte("server.port: 9000\n"),
+		"application-local.yml": []byte("server: [\n"),
+		"application-cloud.yml": []byte("server.port: 9443\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(properties.Resolve(ActiveProfiles("cloud")), map[string]string{
+		"server.port": "9443",
+	}); diff != "" {
+		t.Errorf("active profiles (-expected, +actual) = %v", diff)
+	}
+	if _, ok := invalid["local"]; !ok || len(invalid) != 1 {
+		t.Errorf("expected the local profile to be invalid, got %v", invalid)
+	}
+}
+
+func TestParseApplicationProperties_ProfileExpressions(t *testing.T) {
+	properties, _, err := parseApplicationProperties(map[string][]byte{
+		"application.yml": []byte(`
+greeting: hello
+---
+spring.profiles: dev,local
+greeting: hello developer
+---
+spring.config.activate.on-profile: "!prod"
+debug: true
+---
+spring.config.activate.on-profile: cloud & (kubernetes | cf)
+server.port: 9443
+---
+spring:
+  profiles:
+  - staging
+  - qa
+greeting: hello tester
+---
+spring.config.activate.on-profile: cloud & kubernetes | cf
+invalid: true
+`),
+		"application-local.yml": []byte("greeting: hello local\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		profiles string
+		expected map[string]string
+	}{{
+		profiles: "",
+		expected: map[string]string{"greeting": "hello", "debug": "true"},
+	}, {
+		profiles: "dev",
+		expected: map[string]string{"greeting": "hello developer", "debug": "true", "spring.profiles": "dev,local"},
+	}, {
+		// the profile file and the document share the profile
+		profiles: "local",
+		expected: map[string]string{"greeting": "hello local", "debug": "true", "spring.profiles": "dev,local"},
+	}, {
+		profiles: "prod",
+		expected: map[string]string{"greeting": "hello"},
+	}, {
+		profiles: "qa",
+		expected: map[string]string{"greeting": "hello tester", "debug": "true", "spring.profiles[0]": "staging", "spring.profiles[1]": "qa"},
+	}, {
+		profiles: "cloud",
+		expected: map[string]string{"greeting": "hello", "debug": "true"},
+	}, {
+		profiles: "cloud,kubernetes,prod",
+		expected: map[string]string{"greeting": "hello", "server.port": "9443"},
+	}, {
+		// later profiles take precedence
+		profiles: "dev,qa",
+		expected: map[string]string{"greeting": "hello tester", "debug": "true", "spring.profiles": "dev,local", "spring.profiles[0]": "staging", "spring.profiles[1]": "qa"},
+	}, {
+		profiles: "qa,dev",
+		expected: map[string]string{"greeting": "hello developer", "debug": "true", "spring.profiles": "dev,local", "spring.profiles[0]": "staging", "spring.profiles[1]": "qa"},
+	}}
+	for _, test := range tests {
+		t.Run(test.profiles, func(t *testing.T) {
+			resolved := properties.Resolve(ActiveProfiles(test.profiles))
+			// the activating property is merged with its document
+			delete(resolved, "spring.config.activate.on-profile")
+			if diff := cmp.Diff(test.expected, resolved); diff != "" {
+				t.Errorf("properties (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestMatchProfiles(t *testing.T) {
+	tests := []struct {
+		expression string
+		profiles   []string
+		expected   bool
+	}{
+		{"dev", []string{"dev"}, true},
+		{"dev", []string{"prod"}, false},
+		{"dev, local", []string{"local"}, true},
+		{"!prod", nil, true},
+		{"!prod", []string{"prod"}, false},
+		{"cloud & kubernetes", []string{"cloud"}, false},
+		{"cloud & kubernetes", []string{"kubernetes", "cloud"}, true},
+		{"cloud | kubernetes", []string{"kubernetes"}, true},
+		{"cloud & !(dev | test)", []string{"cloud", "test"}, false},
+		{"cloud & !(dev | test)", []string{"cloud"}, true},
+		// mixed operators require parentheses
+		{"cloud & kubernetes | dev", []string{"dev"}, false},
+		{"(cloud", []string{"cloud"}, false},
+		{"cloud &", []string{"cloud"}, false},
+		{"", []string{"cloud"}, false},
+	}
+	for _, test := range tests {
+		if actual := matchProfiles(test.expression, test.profiles); actual != test.expected {
+			t.Errorf("matchProfiles(%q, %v) = %v, expected %v", test.expression, test.profiles, actual, test.expected)
+		}
+	}
+}
+
+func TestParsePropertiesFile(t *testing.T) {
+	properties := parsePropertiesFile(bytes.NewBufferString(`
+# comment
+! also a comment
+server.port = 9000
+management.server.port:9001
+spring.application.name  demo
+greeting=hello \
+    world
+path=C:\\temp
+unicode=caf\u00e9
+empty
+`))
+	if diff := cmp.Diff(properties, map[string]string{
+		"server.port":             "9000",
+		"management.server.port":  "9001",
+		"spring.application.name": "demo",
+		"greeting":                "hello world",
+		"path":                    `C:\temp`,
+		"unicode":                 "café",
+		"empty":                   "",
+	}); diff != "" {
+		t.Errorf("properties (-expected, +actual) = %v", diff)
+	}
+}
@@ -1,21 +1,213 @@
 package cnb
 
 import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	ggcr "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// Registry resolves image references to images. The keychain provides the
+// credentials for the lookup, registries that don't require credentials may
+// ignore it.
+type Registry interface {
+	GetImage(ref string, keychain authn.Keychain) (ggcr.Image, error)
+}
+
+var (
+	_ Registry = (*RemoteRegistry)(nil)
+	_ Registry = (*LayoutRegistry)(nil)
+	_ Registry = (*TarballRegistry)(nil)
 )
 
-type Registry struct {
+// RemoteRegistry reads images from remote container registries. The Keychain
+// is used for lookups that don't provide a keychain of their own.
+type RemoteRegistry struct {
 	Keychain authn.Keychain
 }
 
-func (r *Registry) GetImage(ref string) (ggcr.Image, error) {
+func (r *RemoteRegistry) GetImage(ref string, keychain authn.Keychain) (ggcr.Image, error) {
+	parsed, err := name.ParseReference(ref, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+	if keychain == nil {
+		keychain = r.Keychain
+	}
+	return remote.Image(parsed, remote.WithAuthFromKeychain(keychain))
+}
+
+// IsUnauthorized returns true if the registry rejected the request for lack
+// of valid credentials.
+func IsUnauthorized(err error) bool {
+	var terr *transport.Error
+	if !errors.As(err, &terr) {
+		return false
+	}
+	return terr.StatusCode == http.StatusUnauthorized || terr.StatusCode == http.StatusForbidden
+}
+
+const ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+// LayoutRegistry reads images from an OCI image layout directory. Images are
+// matched by digest, or by the `org.opencontainers.image.ref.name`
+// annotation in the layout's index, which may hold either a full image
+// reference or just the tag.
+type LayoutRegistry struct {
+	Path string
+}
+
+func (r *LayoutRegistry) GetImage(ref string, keychain authn.Keychain) (ggcr.Image, error) {
+	parsed, err := name.ParseReference(ref, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+	path, err := layout.FromPath(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	index, err := path.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range manifest.Manifests {
+		if desc.MediaType != types.OCIManifestSchema1 && desc.MediaType != types.DockerManifestSchema2 {
+			continue
+		}
+		if layoutDescriptorMatches(desc, parsed) {
+			return index.Image(desc.Digest)
+		}
+	}
+	return nil, fmt.Errorf("image %s not found in layout %s", ref, r.Path)
+}
+
+func layoutDescriptorMatches(desc ggcr.Descriptor, ref name.Reference) bool {
+	switch r := ref.(type) {
+	case name.Digest:
+		return desc.Digest.String() == r.DigestStr()
+	case name.Tag:
+		refName, ok := desc.Annotations[ociRefNameAnnotation]
+		if !ok {
+			return false
+		}
+		if refName == r.TagStr() {
+			return true
+		}
+		if tag, err := name.NewTag(refName, name.WeakValidation); err == nil {
+			// compare the resolved names, since there are several ways to specify the same tag
+			return tag.Name() == r.Name()
+		}
+	}
+	return false
+}
+
+// TarballRegistry reads images from a tarball created by `docker save`.
+// Images are matched by their repo tags, or by digest.
+type TarballRegistry struct {
+	Path string
+}
+
+func (r *TarballRegistry) GetImage(ref string, keychain authn.Keychain) (ggcr.Image, error) {
 	parsed, err := name.ParseReference(ref, name.WeakValidation)
 	if err != nil {
 		return nil, err
 	}
-	// TODO add an LRU cache to avoid slamming the remote registry with requests
-	return remote.Image(parsed, remote.WithAuthFromKeychain(r.Keychain))
+	switch p := parsed.(type) {
+	case name.Tag:
+		return tarball.ImageFromPath(r.Path, &p)
+	case name.Digest:
+		manifest, err := r.manifest()
+		if err != nil {
+			return nil, err
+		}
+		if len(manifest) == 1 && len(manifest[0].RepoTags) == 0 {
+			return r.imageWithDigest(nil, p)
+		}
+		for _, desc := range manifest {
+			for _, repoTag := range desc.RepoTags {
+				tag, err := name.NewTag(repoTag, name.WeakValidation)
+				if err != nil {
+					return nil, err
+				}
+				if img, err := r.imageWithDigest(&tag, p); err == nil {
+					return img, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("image %s not found in tarball %s", ref, r.Path)
+}
+
+func (r *TarballRegistry) imageWithDigest(tag *name.Tag, digest name.Digest) (ggcr.Image, error) {
+	img, err := tarball.ImageFromPath(r.Path, tag)
+	if err != nil {
+		return nil, err
+	}
+	d, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if d.String() != digest.DigestStr() {
+		return nil, fmt.Errorf("image %s has digest %s", tag, d)
+	}
+	return img, nil
+}
+
+func (r *TarballRegistry) manifest() (tarball.Manifest, error) {
+	f, err := os.Open(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tf := tar.NewReader(f)
+	for {
+		hdr, err := tf.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("manifest.json not found in tarball %s", r.Path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == "manifest.json" {
+			var manifest tarball.Manifest
+			if err := json.NewDecoder(tf).Decode(&manifest); err != nil {
+				return nil, err
+			}
+			return manifest, nil
+		}
+	}
+}
+
+// DigestedReference pins the image reference to the digest of the resolved
+// image, so a moving tag always resolves to the same image content.
+func DigestedReference(ref string, img ggcr.Image) (string, error) {
+	parsed, err := name.ParseReference(ref, name.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return "", err
+	}
+	return digestedReference(parsed, digest.String()), nil
+}
+
+func digestedReference(ref name.Reference, digest string) string {
+	return fmt.Sprintf("%s@%s", ref.Context().Name(), digest)
 }
@@ -0,0 +1,175 @@
+package cnb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestDigestedReference(t *testing.T) {
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected string
+	}{{
+		name:     "tagged",
+		ref:      "example.com/repo/app:latest",
+		expected: "example.com/repo/app@" + digest.String(),
+	}, {
+		name:     "implicit tag",
+		ref:      "example.com/repo/app",
+		expected: "example.com/repo/app@" + digest.String(),
+	}, {
+		name:     "docker hub",
+		ref:      "scothis/petclinic:sbo-20200304",
+		expected: "index.docker.io/scothis/petclinic@" + digest.String(),
+	}, {
+		name:     "digested",
+		ref:      "example.com/repo/app@" + digest.String(),
+		expected: "example.com/repo/app@" + digest.String(),
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := DigestedReference(test.ref, img)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLayoutRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layout")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tagged := randomImage(t)
+	if err := path.AppendImage(tagged, layout.WithAnnotations(map[string]string{
+		"org.opencontainers.image.ref.name": "example.com/repo/app:1.0.0",
+	})); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	shortTagged := randomImage(t)
+	if err := path.AppendImage(shortTagged, layout.WithAnnotations(map[string]string{
+		"org.opencontainers.image.ref.name": "2.0.0",
+	})); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	registry := &LayoutRegistry{Path: dir}
+	assertGetImage(t, registry, "example.com/repo/app:1.0.0", tagged)
+	assertGetImage(t, registry, "example.com/repo/app:2.0.0", shortTagged)
+	assertGetImage(t, registry, "example.com/repo/app@"+digestOf(t, tagged), tagged)
+	if _, err := registry.GetImage("example.com/repo/app:3.0.0", nil); err == nil {
+		t.Errorf("expected error for missing image")
+	}
+}
+
+func TestTarballRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tarball")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	img1 := randomImage(t)
+	img2 := randomImage(t)
+	tag1, _ := name.NewTag("example.com/repo/app:1.0.0")
+	tag2, _ := name.NewTag("example.com/repo/app:2.0.0")
+	path := filepath.Join(dir, "images.tar")
+	if err := tarball.MultiWriteToFile(path, map[name.Tag]v1.Image{
+		tag1: img1,
+		tag2: img2,
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	registry := &TarballRegistry{Path: path}
+	assertGetImage(t, registry, "example.com/repo/app:1.0.0", img1)
+	assertGetImage(t, registry, "example.com/repo/app:2.0.0", img2)
+	assertGetImage(t, registry, "example.com/repo/app@"+digestOf(t, img2), img2)
+	if _, err := registry.GetImage("example.com/repo/app:3.0.0", nil); err == nil {
+		t.Errorf("expected error for missing image")
+	}
+}
+
+func assertGetImage(t *testing.T, registry Registry, ref string, expected v1.Image) {
+	t.Helper()
+	img, err := registry.GetImage(ref, nil)
+	if err != nil {
+		t.Fatalf("unexpected error getting %s: %s", ref, err)
+	}
+	if actual, expected := digestOf(t, img), digestOf(t, expected); actual != expected {
+		t.Errorf("expected %s to have digest %s, got %s", ref, expected, actual)
+	}
+}
+
+func randomImage(t *testing.T) v1.Image {
+	t.Helper()
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return img
+}
+
+func digestOf(t *testing.T, img v1.Image) string {
+	t.Helper()
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return digest.String()
+}
+
+func TestRemoteRegistry_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	registry := &RemoteRegistry{Keychain: authn.DefaultKeychain}
+	_, err = registry.GetImage(u.Host+"/repo/app:latest", nil)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !IsUnauthorized(fmt.Errorf("wrapped: %w", err)) {
+		t.Errorf("expected unauthorized error, got %s", err)
+	}
+}
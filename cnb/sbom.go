@@ -0,0 +1,209 @@
+package cnb
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const sbomLaunchDir = "layers/sbom/launch/"
+
+const (
+	cycloneDXSBOMFile = "sbom.cdx.json"
+	spdxSBOMFile      = "sbom.spdx.json"
+)
+
+// ParseSBOMDependencies normalizes the dependencies reported by the SBOM
+// layer newer lifecycles add to an image. The layer holds SBOM files for each
+// buildpack and buildpack layer, CycloneDX files are preferred over SPDX
+// files for the same buildpack layer. Images without an SBOM layer have no
+// SBOM dependencies.
+func ParseSBOMDependencies(img v1.Image, lifecycle LifecycleMetadata, buildpacks []Buildpack) ([]Dependency, error) {
+	if lifecycle.SBOM == nil || lifecycle.SBOM.SHA == "" {
+		return []Dependency{}, nil
+	}
+	diffID, err := v1.NewHash(lifecycle.SBOM.SHA)
+	if err != nil {
+		return nil, err
+	}
+	layer, err := findLayer(img, diffID)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// buildpack ids are escaped in the layer's paths
+	escapedBuildpacks := map[string]Buildpack{}
+	for _, bp := range buildpacks {
+		escapedBuildpacks[strings.ReplaceAll(bp.ID, "/", "_")] = bp
+	}
+
+	files := map[string][]Dependency{}
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(hdr.Name, "/")
+		if !strings.HasPrefix(name, sbomLaunchDir) {
+			continue
+		}
+		var deps []Dependency
+		switch path.Base(name) {
+		case cycloneDXSBOMFile:
+			deps, err = parseCycloneDX(tr)
+		case spdxSBOMFile:
+			deps, err = parseSPDX(tr)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		escapedID := strings.SplitN(strings.TrimPrefix(name, sbomLaunchDir), "/", 2)[0]
+		bp, ok := escapedBuildpacks[escapedID]
+		if !ok {
+			bp = Buildpack{ID: escapedID}
+		}
+		for i := range deps {
+			deps[i].Buildpack = bp
+		}
+		files[name] = deps
+	}
+
+	names := []string{}
+	for name := range files {
+		dir, base := path.Split(name)
+		if base == spdxSBOMFile {
+			if _, ok := files[dir+cycloneDXSBOMFile]; ok {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	deps := [][]Dependency{}
+	for _, name := range names {
+		deps = append(deps, files[name])
+	}
+	return mergeDependencies(deps...), nil
+}
+
+func findLayer(img v1.Image, diffID v1.Hash) (v1.Layer, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range layers {
+		id, err := layer.DiffID()
+		if err != nil {
+			return nil, err
+		}
+		if id == diffID {
+			return layer, nil
+		}
+	}
+	return nil, fmt.Errorf("layer %s not found in image", diffID)
+}
+
+func parseCycloneDX(r io.Reader) ([]Dependency, error) {
+	var doc CycloneDX
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	deps := []Dependency{}
+	var walk func(components []CycloneDXComponent)
+	walk = func(components []CycloneDXComponent) {
+		for _, c := range components {
+			d := Dependency{
+				Name:    c.Name,
+				Version: c.Version,
+				PURL:    c.PURL,
+				Kind:    dependencyKindForPURL(c.PURL),
+			}
+			for _, h := range c.Hashes {
+				if h.Algorithm == "SHA-256" {
+					d.Sha256 = h.Content
+				}
+			}
+			for _, l := range c.Licenses {
+				switch {
+				case l.License != nil && l.License.ID != "":
+					d.Licenses = append(d.Licenses, l.License.ID)
+				case l.License != nil && l.License.Name != "":
+					d.Licenses = append(d.Licenses, l.License.Name)
+				case l.Expression != "":
+					d.Licenses = append(d.Licenses, l.Expression)
+				}
+			}
+			deps = append(deps, d)
+			walk(c.Components)
+		}
+	}
+	walk(doc.Components)
+	return deps, nil
+}
+
+type spdxDocument struct {
+	Packages []struct {
+		Name        string `json:"name"`
+		VersionInfo string `json:"versionInfo"`
+		Checksums   []struct {
+			Algorithm     string `json:"algorithm"`
+			ChecksumValue string `json:"checksumValue"`
+		} `json:"checksums"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		LicenseDeclared  string `json:"licenseDeclared"`
+		ExternalRefs     []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+func parseSPDX(r io.Reader) ([]Dependency, error) {
+	var doc spdxDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	deps := []Dependency{}
+	for _, p := range doc.Packages {
+		d := Dependency{
+			Name:    p.Name,
+			Version: p.VersionInfo,
+		}
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				d.PURL = ref.ReferenceLocator
+			}
+		}
+		d.Kind = dependencyKindForPURL(d.PURL)
+		for _, c := range p.Checksums {
+			if c.Algorithm == "SHA256" {
+				d.Sha256 = c.ChecksumValue
+			}
+		}
+		for _, license := range []string{p.LicenseDeclared, p.LicenseConcluded} {
+			if license != "" && license != "NOASSERTION" && license != "NONE" {
+				d.Licenses = []string{license}
+				break
+			}
+		}
+		deps = append(deps, d)
+	}
+	return deps, nil
+}
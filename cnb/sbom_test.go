@@ -0,0 +1,159 @@
+package cnb
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestParseSBOMDependencies(t *testing.T) {
+	layer := sbomLayer(t, map[string]string{
+		"layers/sbom/launch/paketo-buildpacks_bellsoft-liberica/jre/sbom.spdx.json": testSPDX,
+		"layers/sbom/launch/paketo-buildpacks_spring-boot/sbom.cdx.json":            testCycloneDX,
+		// the CycloneDX document is preferred when both formats are present
+		"layers/sbom/launch/paketo-buildpacks_spring-boot/sbom.spdx.json": `{"packages": [{"name": "ignored"}]}`,
+	})
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	diffID, err := layer.DiffID()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	lifecycle := LifecycleMetadata{
+		SBOM: &LayerMetadata{SHA: diffID.String()},
+	}
+	buildpacks := []Buildpack{
+		{ID: "paketo-buildpacks/bellsoft-liberica", Version: "9.0.0"},
+		{ID: "paketo-buildpacks/spring-boot", Version: "5.0.0"},
+	}
+
+	deps, err := ParseSBOMDependencies(img, lifecycle, buildpacks)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(deps, []Dependency{
+		{
+			Name:      "BellSoft Liberica JRE",
+			Version:   "11.0.12",
+			Sha256:    "jre-sha",
+			PURL:      "pkg:generic/bellsoft-jre@11.0.12?arch=amd64",
+			Licenses:  []string{"GPL-2.0 WITH Classpath-exception-2.0"},
+			Kind:      RuntimeDependency,
+			Buildpack: Buildpack{ID: "paketo-buildpacks/bellsoft-liberica", Version: "9.0.0"},
+		},
+		{
+			Name:      "spring-boot",
+			Version:   "2.5.4",
+			Sha256:    "boot-sha",
+			PURL:      "pkg:maven/org.springframework.boot/spring-boot@2.5.4",
+			Licenses:  []string{"Apache-2.0"},
+			Kind:      LibraryDependency,
+			Buildpack: Buildpack{ID: "paketo-buildpacks/spring-boot", Version: "5.0.0"},
+		},
+		{
+			Name:      "spring-boot-actuator",
+			Version:   "2.5.4",
+			PURL:      "pkg:maven/org.springframework.boot/spring-boot-actuator@2.5.4",
+			Kind:      LibraryDependency,
+			Buildpack: Buildpack{ID: "paketo-buildpacks/spring-boot", Version: "5.0.0"},
+		},
+	}); diff != "" {
+		t.Errorf("dependencies (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestParseSBOMDependencies_NoSBOM(t *testing.T) {
+	deps, err := ParseSBOMDependencies(empty.Image, LifecycleMetadata{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(deps) != 0 {
+		t.Errorf("expected no dependencies, got %v", deps)
+	}
+}
+
+func sbomLayer(t *testing.T, files map[string]string) v1.Layer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(content)),
+		}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	layer, err := tarball.LayerFromReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return layer
+}
+
+var testCycloneDX = `
+{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.3",
+  "components": [
+    {
+      "type": "library",
+      "group": "org.springframework.boot",
+      "name": "spring-boot",
+      "version": "2.5.4",
+      "purl": "pkg:maven/org.springframework.boot/spring-boot@2.5.4",
+      "hashes": [
+        {"alg": "SHA-1", "content": "boot-sha1"},
+        {"alg": "SHA-256", "content": "boot-sha"}
+      ],
+      "licenses": [
+        {"license": {"id": "Apache-2.0"}}
+      ],
+      "components": [
+        {
+          "type": "library",
+          "group": "org.springframework.boot",
+          "name": "spring-boot-actuator",
+          "version": "2.5.4",
+          "purl": "pkg:maven/org.springframework.boot/spring-boot-actuator@2.5.4"
+        }
+      ]
+    }
+  ]
+}
+`
+
+var testSPDX = `
+{
+  "spdxVersion": "SPDX-2.2",
+  "packages": [
+    {
+      "name": "BellSoft Liberica JRE",
+      "versionInfo": "11.0.12",
+      "checksums": [
+        {"algorithm": "SHA256", "checksumValue": "jre-sha"}
+      ],
+      "licenseConcluded": "NOASSERTION",
+      "licenseDeclared": "GPL-2.0 WITH Classpath-exception-2.0",
+      "externalRefs": [
+        {"referenceCategory": "PACKAGE_MANAGER", "referenceType": "purl", "referenceLocator": "pkg:generic/bellsoft-jre@11.0.12?arch=amd64"}
+      ]
+    }
+  ]
+}
+`
@@ -0,0 +1,275 @@
+package cnb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// cosignSignatureAnnotation holds the base64 encoded signature of a
+	// signature image layer's payload
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	signatureTagSuffix        = ".sig"
+	// maxSignaturePayloadSize limits the payloads read from a signature
+	// image, which are small JSON documents
+	maxSignaturePayloadSize = 64 * 1024
+)
+
+// SignatureMissingError indicates the image does not have a signature image
+type SignatureMissingError struct {
+	Ref string
+	Err error
+}
+
+func (e *SignatureMissingError) Error() string {
+	return fmt.Sprintf("signature %s not found: %s", e.Ref, e.Err)
+}
+
+func (e *SignatureMissingError) Unwrap() error {
+	return e.Err
+}
+
+// SignatureReference returns the reference of the signature image for the
+// image reference pinned to a digest, the `sha256-<digest>.sig` tag in the
+// image's repository.
+func SignatureReference(ref string) (string, error) {
+	digest, err := name.NewDigest(ref, name.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+	tag := strings.Replace(digest.DigestStr(), ":", "-", 1) + signatureTagSuffix
+	return digest.Context().Tag(tag).Name(), nil
+}
+
+// simpleSigningPayload is the payload signed by cosign
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// SignatureVerifier verifies the detached signatures of images, stored
+// alongside the image in its registry by cosign. Successful verifications are
+// cached by the image's repository and digest, and the keys the image was
+// verified with.
+type SignatureVerifier struct {
+	Registry Registry
+
+	verified *lru.Cache
+}
+
+func NewSignatureVerifier(registry Registry, size int) *SignatureVerifier {
+	verified, err := lru.New(size)
+	if err != nil {
+		// if called with an invalid size
+		panic(err)
+	}
+	return &SignatureVerifier{
+		Registry: registry,
+		verified: verified,
+	}
+}
+
+// Verify checks the image reference, pinned to a digest, has a signature for
+// the digest made by one of the keys.
+func (v *SignatureVerifier) Verify(ref string, keys []crypto.PublicKey, keychain authn.Keychain) error {
+	if len(keys) == 0 {
+		return errors.New("no public keys to verify signatures with")
+	}
+	digest, err := name.NewDigest(ref, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	// signatures are bound to the repository as well as the digest
+	cacheKey, err := signatureCacheKey(digest.Name(), keys)
+	if err != nil {
+		return err
+	}
+	if _, ok := v.verified.Get(cacheKey); ok {
+		return nil
+	}
+
+	sigRef, err := SignatureReference(ref)
+	if err != nil {
+		return err
+	}
+	sigImg, err := v.Registry.GetImage(sigRef, keychain)
+	if err != nil {
+		return &SignatureMissingError{Ref: sigRef, Err: err}
+	}
+	manifest, err := sigImg.Manifest()
+	if err != nil {
+		return err
+	}
+	for _, desc := range manifest.Layers {
+		encoded, ok := desc.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		layer, err := sigImg.LayerByDigest(desc.Digest)
+		if err != nil {
+			return err
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			return err
+		}
+		payload, err := readPayload(rc)
+		if err != nil {
+			return err
+		}
+		if !verifySignature(keys, payload, signature) {
+			continue
+		}
+		var p simpleSigningPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			continue
+		}
+		// the signature must be for this image, not another image in the
+		// repository or the same image in another repository
+		if p.Critical.Image.DockerManifestDigest != digest.DigestStr() || !sameRepository(p.Critical.Identity.DockerReference, digest.Context()) {
+			continue
+		}
+		v.verified.Add(cacheKey, true)
+		return nil
+	}
+	return fmt.Errorf("no valid signature for image %s in %s", ref, sigRef)
+}
+
+// sameRepository returns true when the docker reference of a signature names
+// the repository
+func sameRepository(dockerReference string, repo name.Repository) bool {
+	signed, err := name.ParseReference(dockerReference, name.WeakValidation)
+	if err != nil {
+		return false
+	}
+	return signed.Context().Name() == repo.Name()
+}
+
+// readPayload reads a signature payload, which is normally stored
+// uncompressed. Payloads larger than maxSignaturePayloadSize, compressed or
+// not, are rejected.
+func readPayload(rc io.ReadCloser) ([]byte, error) {
+	defer rc.Close()
+	content, err := readSignaturePayload(rc)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > 2 && content[0] == 0x1f && content[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return readSignaturePayload(gz)
+	}
+	return content, nil
+}
+
+func readSignaturePayload(r io.Reader) ([]byte, error) {
+	content, err := ioutil.ReadAll(io.LimitReader(r, maxSignaturePayloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxSignaturePayloadSize {
+		return nil, fmt.Errorf("signature payload exceeds %d bytes", maxSignaturePayloadSize)
+	}
+	return content, nil
+}
+
+func verifySignature(keys []crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			var sig struct{ R, S *big.Int }
+			if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) != 0 {
+				continue
+			}
+			if ecdsa.Verify(k, hash[:], sig.R, sig.S) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, payload, signature) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func signatureCacheKey(ref string, keys []crypto.PublicKey) (string, error) {
+	fingerprints := []string{}
+	for _, key := range keys {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		fingerprint := sha256.Sum256(der)
+		fingerprints = append(fingerprints, hex.EncodeToString(fingerprint[:]))
+	}
+	sort.Strings(fingerprints)
+	return ref + "/" + strings.Join(fingerprints, ","), nil
+}
+
+// ParsePublicKeys parses the PEM encoded `PUBLIC KEY` blocks in the content.
+// ECDSA, RSA and Ed25519 keys are supported.
+func ParsePublicKeys(content []byte) ([]crypto.PublicKey, error) {
+	keys := []crypto.PublicKey{}
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	}
+	return keys, nil
+}
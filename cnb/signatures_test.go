@@ -0,0 +1,172 @@
+package cnb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestSignatureReference(t *testing.T) {
+	ref, err := SignatureReference("registry.example.com/repo/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "registry.example.com/repo/app:sha256-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef.sig"; ref != expected {
+		t.Errorf("expected %q, got %q", expected, ref)
+	}
+	if _, err := SignatureReference("registry.example.com/repo/app:latest"); err == nil {
+		t.Errorf("expected error for reference without a digest")
+	}
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	signed := u.Host + "/repo/app@" + pushImage(t, u.Host+"/repo/app:signed", testLabel)
+	unsigned := u.Host + "/repo/app@" + pushImage(t, u.Host+"/repo/app:unsigned", testLabel)
+	key := testKey(t)
+	pushSignature(t, signed, signed, key)
+	// a signature for another image doesn't verify the image
+	misdirected := u.Host + "/repo/app@" + pushImage(t, u.Host+"/repo/app:misdirected", testLabel)
+	pushSignature(t, misdirected, signed, key)
+	// nor does a signature for the same digest in another repository
+	copiedDigest := pushImage(t, u.Host+"/repo/copied:latest", testLabel)
+	copied := u.Host + "/repo/copied@" + copiedDigest
+	pushSignature(t, copied, u.Host+"/other/app@"+copiedDigest, key)
+
+	keys, err := ParsePublicKeys(testPublicKeyPEM(t, key))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	otherKeys := []crypto.PublicKey{testKey(t).Public()}
+
+	v := NewSignatureVerifier(&RemoteRegistry{Keychain: authn.DefaultKeychain}, 10)
+	if err := v.Verify(signed, keys, nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := v.Verify(signed, otherKeys, nil); err == nil {
+		t.Errorf("expected error for signature made by another key")
+	}
+	if err := v.Verify(misdirected, keys, nil); err == nil {
+		t.Errorf("expected error for signature of another image")
+	}
+	if err := v.Verify(copied, keys, nil); err == nil {
+		t.Errorf("expected error for signature of another repository")
+	}
+	var missing *SignatureMissingError
+	if err := v.Verify(unsigned, keys, nil); !errors.As(err, &missing) {
+		t.Errorf("expected signature missing error, got %v", err)
+	}
+}
+
+func TestReadPayload(t *testing.T) {
+	payload := []byte(`{"critical":{}}`)
+	if content, err := readPayload(ioutil.NopCloser(bytes.NewReader(payload))); err != nil || !bytes.Equal(content, payload) {
+		t.Errorf("expected payload %q, got %q, %v", payload, content, err)
+	}
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	gz.Write(payload)
+	gz.Close()
+	if content, err := readPayload(ioutil.NopCloser(compressed)); err != nil || !bytes.Equal(content, payload) {
+		t.Errorf("expected payload %q, got %q, %v", payload, content, err)
+	}
+
+	oversized := make([]byte, maxSignaturePayloadSize+1)
+	if _, err := readPayload(ioutil.NopCloser(bytes.NewReader(oversized))); err == nil {
+		t.Errorf("expected error for an oversized payload")
+	}
+	// compresses to a fraction of the limit
+	bomb := &bytes.Buffer{}
+	gz = gzip.NewWriter(bomb)
+	gz.Write(oversized)
+	gz.Close()
+	if _, err := readPayload(ioutil.NopCloser(bomb)); err == nil {
+		t.Errorf("expected error for an oversized compressed payload")
+	}
+}
+
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return key
+}
+
+func testPublicKeyPEM(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// pushSignature signs the subject image and pushes the signature as the
+// signature of the image ref
+func pushSignature(t *testing.T, ref, subject string, key *ecdsa.PrivateKey) {
+	t.Helper()
+	digest, err := name.NewDigest(subject, name.WeakValidation)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest.Context().Name(), digest.DigestStr()))
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	layer, err := tarball.LayerFromReader(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: layer,
+		Annotations: map[string]string{
+			cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sigRef, err := SignatureReference(ref)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tag, err := name.NewTag(sigRef, name.WeakValidation)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := remote.Write(tag, img); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,67 @@
+package cnb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const stackPackagesLabel = "io.paketo.stack.packages"
+
+type stackPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+	Source  struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"source"`
+}
+
+// ParseStackPackages reads the operating system packages of the run image,
+// listed by Paketo stacks in the io.paketo.stack.packages label. Stacks
+// without the label have no packages.
+func ParseStackPackages(img v1.Image) ([]Dependency, error) {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	label, ok := cfg.Config.Labels[stackPackagesLabel]
+	if !ok {
+		return []Dependency{}, nil
+	}
+	var packages []stackPackage
+	if err := json.Unmarshal([]byte(label), &packages); err != nil {
+		return nil, err
+	}
+	stackID := cfg.Config.Labels[stackIDLabel]
+	deps := make([]Dependency, len(packages))
+	for i, p := range packages {
+		deps[i] = Dependency{
+			Name:    p.Name,
+			Version: p.Version,
+			Kind:    OSDependency,
+		}
+		if stackID == "io.buildpacks.stacks.bionic" || stackID == "io.paketo.stacks.tiny" {
+			deps[i].PURL = debianPURL(p)
+		}
+	}
+	return deps, nil
+}
+
+func debianPURL(p stackPackage) string {
+	purl := fmt.Sprintf("pkg:deb/ubuntu/%s@%s", url.PathEscape(p.Name), url.PathEscape(p.Version))
+	q := url.Values{}
+	if p.Arch != "" {
+		q.Set("arch", p.Arch)
+	}
+	if p.Source.Name != "" && p.Source.Name != p.Name {
+		q.Set("upstream", p.Source.Name)
+	}
+	if len(q) != 0 {
+		purl += "?" + q.Encode()
+	}
+	return purl
+}
@@ -0,0 +1,131 @@
+package cnb
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// qualifier ordering for maven style versions, releases sort after
+// pre-releases and before service packs
+var versionQualifiers = map[string]int{
+	"alpha":     1,
+	"a":         1,
+	"beta":      2,
+	"b":         2,
+	"milestone": 3,
+	"m":         3,
+	"rc":        4,
+	"cr":        4,
+	"snapshot":  5,
+	"":          6,
+	"ga":        6,
+	"final":     6,
+	"release":   6,
+	"sp":        7,
+}
+
+// CompareVersions compares versions with maven ordering, which orders numeric
+// parts numerically and pre-release qualifiers like `M1` and `RC1` before the
+// release. It returns -1, 0 or 1 when a is less than, equal to or greater
+// than b.
+func CompareVersions(a, b string) int {
+	ai, bi := versionItems(a), versionItems(b)
+	for i := 0; i < len(ai) || i < len(bi); i++ {
+		var x, y string
+		if i < len(ai) {
+			x = ai[i]
+		}
+		if i < len(bi) {
+			y = bi[i]
+		}
+		if c := compareVersionItems(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareVersionItems(x, y string) int {
+	xn, xerr := strconv.ParseUint(x, 10, 64)
+	yn, yerr := strconv.ParseUint(y, 10, 64)
+	xNumeric, yNumeric := xerr == nil, yerr == nil
+	// missing items are zero when compared to a number, and a release when
+	// compared to a qualifier
+	if x == "" && yNumeric {
+		xNumeric = true
+	}
+	if y == "" && xNumeric {
+		yNumeric = true
+	}
+	switch {
+	case xNumeric && yNumeric:
+		switch {
+		case xn < yn:
+			return -1
+		case xn > yn:
+			return 1
+		}
+		return 0
+	case xNumeric:
+		// numbers sort after qualifiers
+		return 1
+	case yNumeric:
+		return -1
+	}
+	xq, xKnown := versionQualifiers[x]
+	yq, yKnown := versionQualifiers[y]
+	switch {
+	case xKnown && yKnown:
+		switch {
+		case xq < yq:
+			return -1
+		case xq > yq:
+			return 1
+		}
+		return 0
+	case xKnown:
+		return -1
+	case yKnown:
+		return 1
+	}
+	return strings.Compare(x, y)
+}
+
+// versionItems splits a version into lower case items at separators and at
+// transitions between digits and letters
+func versionItems(version string) []string {
+	version = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(version, "v"), "V"))
+	items := []string{}
+	current := strings.Builder{}
+	flush := func() {
+		if current.Len() > 0 {
+			items = append(items, current.String())
+			current.Reset()
+		}
+	}
+	var previous rune
+	for i, r := range version {
+		switch {
+		case r == '.' || r == '-' || r == '_' || r == '+':
+			flush()
+		case i > 0 && current.Len() > 0 && unicode.IsDigit(r) != unicode.IsDigit(previous):
+			flush()
+			current.WriteRune(r)
+		default:
+			current.WriteRune(r)
+		}
+		previous = r
+	}
+	flush()
+	// trailing zeros and release qualifiers don't change the version
+	for len(items) > 0 {
+		last := items[len(items)-1]
+		if q, ok := versionQualifiers[last]; (ok && q == versionQualifiers[""]) || last == "0" {
+			items = items[:len(items)-1]
+			continue
+		}
+		break
+	}
+	return items
+}
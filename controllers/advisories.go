@@ -0,0 +1,222 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectriff/system/pkg/controllers"
+	mononokev1alpha1 "github.com/spring-cloud-incubator/mononoke/api/v1alpha1"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// maxReportedVulnerabilities limits the number of vulnerabilities listed in
+// the status, keeping the status of applications with many vulnerable
+// dependencies small
+const maxReportedVulnerabilities = 10
+
+// VulnerabilityPolicy matches the dependencies of applications against a
+// local advisory database. Applications with a vulnerability at or above the
+// threshold are not rolled out. Vulnerabilities are reported, but never block
+// a rollout, when the threshold is empty.
+type VulnerabilityPolicy struct {
+	Advisories AdvisorySource
+	Threshold  cnb.Severity
+}
+
+// AdvisorySource provides the advisory database. Advisories are never
+// fetched from the network, so the database works in air-gapped clusters.
+type AdvisorySource interface {
+	Advisories(ctx context.Context, c client.Reader) (*cnb.AdvisoryDatabase, error)
+}
+
+// DirectoryAdvisorySource reads OSV advisories from a directory, like a
+// mounted volume. The database is reloaded when the files change.
+type DirectoryAdvisorySource struct {
+	Path string
+
+	m           sync.Mutex
+	fingerprint string
+	db          *cnb.AdvisoryDatabase
+}
+
+func (s *DirectoryAdvisorySource) Advisories(ctx context.Context, c client.Reader) (*cnb.AdvisoryDatabase, error) {
+	fingerprint, err := directoryFingerprint(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.db == nil || s.fingerprint != fingerprint {
+		db, err := cnb.LoadAdvisoryDirectory(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		s.db, s.fingerprint = db, fingerprint
+	}
+	return s.db, nil
+}
+
+// directoryFingerprint changes when a file in the directory is added, removed
+// or modified
+func directoryFingerprint(dir string) (string, error) {
+	var count int64
+	var latest time.Time
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		// follow links, like the links to the files of a ConfigMap volume
+		if info, err = os.Stat(path); err != nil {
+			return err
+		}
+		count++
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d", count, latest.UnixNano()), nil
+}
+
+// ConfigMapAdvisorySource reads OSV advisories from the values of a
+// ConfigMap. The database is reloaded when the ConfigMap changes.
+type ConfigMapAdvisorySource struct {
+	ConfigMap types.NamespacedName
+
+	m               sync.Mutex
+	resourceVersion string
+	db              *cnb.AdvisoryDatabase
+}
+
+func (s *ConfigMapAdvisorySource) Advisories(ctx context.Context, c client.Reader) (*cnb.AdvisoryDatabase, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(ctx, s.ConfigMap, configMap); err != nil {
+		return nil, err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.db == nil || s.resourceVersion != configMap.ResourceVersion {
+		db := cnb.NewAdvisoryDatabase()
+		for key, value := range configMap.Data {
+			if err := db.Add([]byte(value)); err != nil {
+				return nil, fmt.Errorf("invalid advisory %s in ConfigMap %s: %w", key, s.ConfigMap, err)
+			}
+		}
+		s.db, s.resourceVersion = db, configMap.ResourceVersion
+	}
+	return s.db, nil
+}
+
+func SpringBootApplicationVulnerabilityPolicy(c controllers.Config, policy *VulnerabilityPolicy) controllers.SubReconciler {
+	c.Log = c.Log.WithName("VulnerabilityPolicy")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr ctrl.Manager, bldr *builder.Builder) error {
+			if policy == nil {
+				return nil
+			}
+			if s, ok := policy.Advisories.(*ConfigMapAdvisorySource); ok {
+				// advisories apply to every application
+				requests := springBootApplicationRequests(c)
+				bldr.Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+					ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+						if a.Meta.GetNamespace() != s.ConfigMap.Namespace || a.Meta.GetName() != s.ConfigMap.Name {
+							return nil
+						}
+						return requests(a)
+					}),
+				})
+			}
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) error {
+			parent.Status.Vulnerabilities = nil
+			if policy == nil {
+				parent.Status.MarkVulnerabilitiesAcceptable()
+				return nil
+			}
+			imageMetadata := controllers.RetrieveValue(ctx, ImageMetadataStashKey).(cnb.ImageMetadata)
+			db, err := policy.Advisories.Advisories(ctx, c)
+			if err != nil {
+				err = fmt.Errorf("unable to load advisories: %w", err)
+				parent.Status.MarkAdvisoriesUnavailable(err.Error())
+				return err
+			}
+			blocking := []string{}
+			for _, v := range db.Match(imageMetadata.Dependencies) {
+				// matches are ordered by severity, the most severe are reported
+				if len(parent.Status.Vulnerabilities) < maxReportedVulnerabilities {
+					parent.Status.Vulnerabilities = append(parent.Status.Vulnerabilities, mononokev1alpha1.Vulnerability{
+						ID:           v.Advisory.ID,
+						Aliases:      v.Advisory.Aliases,
+						Summary:      v.Advisory.Summary,
+						Severity:     string(v.Severity),
+						Dependency:   v.Dependency.Name,
+						Version:      v.Dependency.Version,
+						FixedVersion: v.Fixed,
+					})
+				}
+				if policy.Threshold != "" && v.Severity.AtLeast(policy.Threshold) {
+					blocking = append(blocking, fmt.Sprintf("%s %s is affected by %s (%s)", v.Dependency.Name, v.Dependency.Version, v.Advisory.ID, v.Severity))
+				}
+			}
+			if len(blocking) != 0 {
+				// the same dependency may be listed by more than one buildpack
+				sort.Strings(blocking)
+				message := summarizeViolations(uniqueStrings(blocking))
+				parent.Status.MarkVulnerabilitiesFound(message)
+				return fmt.Errorf("image %s has vulnerabilities at or above %s severity: %s", controllers.RetrieveValue(ctx, ResolvedImageStashKey), policy.Threshold, message)
+			}
+			parent.Status.MarkVulnerabilitiesAcceptable()
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func uniqueStrings(sorted []string) []string {
+	unique := []string{}
+	for i, s := range sorted {
+		if i == 0 || !strings.EqualFold(s, sorted[i-1]) {
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
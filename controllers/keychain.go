@@ -0,0 +1,84 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/authn/k8schain"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MissingCredentialsError indicates the image pull secrets or service account
+// referenced by a pod template do not exist.
+type MissingCredentialsError struct {
+	Err error
+}
+
+func (e *MissingCredentialsError) Error() string {
+	return fmt.Sprintf("missing image pull credentials: %s", e.Err)
+}
+
+func (e *MissingCredentialsError) Unwrap() error {
+	return e.Err
+}
+
+// NewImagePullKeychain returns a keychain that resolves registry credentials
+// for the pod template the same way the kubelet does, from the node's
+// credentials, the template's image pull secrets and the image pull secrets
+// of the template's service account. The secrets are only read once the
+// keychain is first used.
+func NewImagePullKeychain(client kubernetes.Interface, namespace string, template *corev1.PodTemplateSpec) authn.Keychain {
+	imagePullSecrets := []string{}
+	for _, s := range template.Spec.ImagePullSecrets {
+		imagePullSecrets = append(imagePullSecrets, s.Name)
+	}
+	return &imagePullKeychain{
+		client: client,
+		options: k8schain.Options{
+			Namespace:          namespace,
+			ServiceAccountName: template.Spec.ServiceAccountName,
+			ImagePullSecrets:   imagePullSecrets,
+		},
+	}
+}
+
+type imagePullKeychain struct {
+	client  kubernetes.Interface
+	options k8schain.Options
+
+	once     sync.Once
+	keychain authn.Keychain
+	err      error
+}
+
+func (k *imagePullKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	k.once.Do(func() {
+		k.keychain, k.err = k8schain.New(k.client, k.options)
+		if apierrs.IsNotFound(k.err) {
+			k.err = &MissingCredentialsError{Err: k.err}
+		}
+	})
+	if k.err != nil {
+		return nil, k.err
+	}
+	return k.keychain.Resolve(target)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/projectriff/system/pkg/controllers"
+	mononokev1alpha1 "github.com/spring-cloud-incubator/mononoke/api/v1alpha1"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// +kubebuilder:rbac:groups=apps.mononoke.local,resources=licensepolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.mononoke.local,resources=buildpackpolicies,verbs=get;list;watch
+
+// maxReportedViolations limits the number of violations named in a condition
+// message
+const maxReportedViolations = 10
+
+func SpringBootApplicationLicensePolicy(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("LicensePolicy")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr ctrl.Manager, bldr *builder.Builder) error {
+			// policies apply to every application
+			bldr.Watches(&source.Kind{Type: &mononokev1alpha1.LicensePolicy{}}, enqueueSpringBootApplications(c))
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) error {
+			imageMetadata := controllers.RetrieveValue(ctx, ImageMetadataStashKey).(cnb.ImageMetadata)
+			policies := &mononokev1alpha1.LicensePolicyList{}
+			if err := c.List(ctx, policies); err != nil {
+				return err
+			}
+			violations := []string{}
+			for _, policy := range policies.Items {
+				for _, d := range policy.Spec.Violations(imageMetadata.Dependencies) {
+					licenses := "unknown license"
+					if len(d.Licenses) != 0 {
+						licenses = strings.Join(d.Licenses, ", ")
+					}
+					violations = append(violations, fmt.Sprintf("%s %s (%s) violates policy %q", d.Name, d.Version, licenses, policy.Name))
+				}
+			}
+			if len(violations) != 0 {
+				message := summarizeViolations(violations)
+				parent.Status.MarkLicenseViolation(message)
+				return fmt.Errorf("image %s is not license compliant: %s", controllers.RetrieveValue(ctx, ResolvedImageStashKey), message)
+			}
+			parent.Status.MarkLicenseCompliant()
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func SpringBootApplicationBuildpackPolicy(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildpackPolicy")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr ctrl.Manager, bldr *builder.Builder) error {
+			// policies apply to every application
+			bldr.Watches(&source.Kind{Type: &mononokev1alpha1.BuildpackPolicy{}}, enqueueSpringBootApplications(c))
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) error {
+			imageMetadata := controllers.RetrieveValue(ctx, ImageMetadataStashKey).(cnb.ImageMetadata)
+			policies := &mononokev1alpha1.BuildpackPolicyList{}
+			if err := c.List(ctx, policies); err != nil {
+				return err
+			}
+			violations := []string{}
+			for _, policy := range policies.Items {
+				selected, err := policy.Spec.Selects(parent)
+				if err != nil {
+					return fmt.Errorf("invalid selector for BuildpackPolicy %q: %w", policy.Name, err)
+				}
+				if !selected {
+					continue
+				}
+				for _, violation := range policy.Spec.Violations(imageMetadata.Buildpacks) {
+					violations = append(violations, fmt.Sprintf("%s, violates policy %q", violation, policy.Name))
+				}
+			}
+			if len(violations) != 0 {
+				message := summarizeViolations(violations)
+				parent.Status.MarkUntrustedBuildpacks(message)
+				return fmt.Errorf("image %s was not built by trusted buildpacks: %s", controllers.RetrieveValue(ctx, ResolvedImageStashKey), message)
+			}
+			parent.Status.MarkBuildpacksTrusted()
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+// enqueueSpringBootApplications requests every SpringBootApplication is
+// reconciled, for resources that affect every application
+func enqueueSpringBootApplications(c controllers.Config) handler.EventHandler {
+	return &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: springBootApplicationRequests(c),
+	}
+}
+
+func springBootApplicationRequests(c controllers.Config) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		apps := &mononokev1alpha1.SpringBootApplicationList{}
+		if err := c.List(context.Background(), apps); err != nil {
+			c.Log.Error(err, "unable to list SpringBootApplications")
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for _, app := range apps.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: app.Namespace, Name: app.Name},
+			})
+		}
+		return requests
+	}
+}
+
+func summarizeViolations(violations []string) string {
+	if len(violations) <= maxReportedViolations {
+		return strings.Join(violations, "; ")
+	}
+	return fmt.Sprintf("%s; and %d more", strings.Join(violations[:maxReportedViolations], "; "), len(violations)-maxReportedViolations)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/projectriff/system/pkg/controllers"
+	mononokev1alpha1 "github.com/spring-cloud-incubator/mononoke/api/v1alpha1"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SBOMConfigMapKey is the key of the CycloneDX document in the SBOM ConfigMap
+const SBOMConfigMapKey = "bom.json"
+
+// maxSBOMSize is the largest document stored in the SBOM ConfigMap, the API
+// server rejects ConfigMaps larger than 1MiB
+const maxSBOMSize = 1024 * 1024
+
+func SpringBootApplicationChildSBOMReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildSBOM")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &mononokev1alpha1.SpringBootApplication{},
+		ChildType:     &corev1.ConfigMap{},
+		ChildListType: &corev1.ConfigMapList{},
+
+		DesiredChild: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) (*corev1.ConfigMap, error) {
+			imageMetadata := controllers.RetrieveValue(ctx, ImageMetadataStashKey).(cnb.ImageMetadata)
+			bom, err := cnb.NewCycloneDX(parent.Status.LatestImage, imageMetadata)
+			if err != nil {
+				return nil, err
+			}
+			content, err := bom.JSON()
+			if err != nil {
+				return nil, err
+			}
+			if len(content) > maxSBOMSize {
+				// a stale document is worse than none
+				parent.Status.SBOMConfigMap = ""
+				parent.Status.MarkSBOMTooLarge(fmt.Sprintf("the bill of materials of image %s is %d bytes, larger than the %d bytes a ConfigMap holds", parent.Status.LatestImage, len(content), maxSBOMSize))
+				return nil, nil
+			}
+
+			child := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						mononokev1alpha1.SpringBootApplicationLabelKey: parent.Name,
+					}),
+					Annotations: make(map[string]string),
+					Name:        fmt.Sprintf("%s-sbom", parent.Name),
+					Namespace:   parent.Namespace,
+				},
+				Data: map[string]string{
+					SBOMConfigMapKey: string(content),
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *mononokev1alpha1.SpringBootApplication, child *corev1.ConfigMap, err error) {
+			if err != nil {
+				if apierrs.IsAlreadyExists(err) {
+					name := err.(apierrs.APIStatus).Status().Details.Name
+					parent.Status.SBOMConfigMap = ""
+					parent.Status.MarkSBOMNotOwned(name)
+				}
+				return
+			}
+			if child == nil {
+				return
+			}
+			parent.Status.SBOMConfigMap = child.Name
+			parent.Status.MarkSBOMReady()
+		},
+		MergeBeforeUpdate: func(current, desired *corev1.ConfigMap) {
+			current.Labels = desired.Labels
+			current.Data = desired.Data
+		},
+		SemanticEquals: func(a1, a2 *corev1.ConfigMap) bool {
+			return equality.Semantic.DeepEqual(a1.Data, a2.Data) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.sbomController",
+		Sanitize: func(child *corev1.ConfigMap) interface{} {
+			return child.Name
+		},
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/projectriff/system/pkg/controllers"
+	mononokev1alpha1 "github.com/spring-cloud-incubator/mononoke/api/v1alpha1"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SignaturePolicy requires the images of applications are signed by one of
+// the public keys in a Secret
+type SignaturePolicy struct {
+	Verifier *cnb.SignatureVerifier
+	// PublicKeys is the Secret holding PEM encoded public keys
+	PublicKeys types.NamespacedName
+}
+
+func SpringBootApplicationVerifySignature(c controllers.Config, policy *SignaturePolicy, kubeClient kubernetes.Interface) controllers.SubReconciler {
+	c.Log = c.Log.WithName("VerifySignature")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) error {
+			if policy == nil {
+				parent.Status.MarkSignatureVerified()
+				return nil
+			}
+			keys, err := signaturePublicKeys(kubeClient, policy.PublicKeys)
+			if err != nil {
+				parent.Status.MarkSignaturePublicKeysInvalid(err.Error())
+				return err
+			}
+			keychain := NewImagePullKeychain(kubeClient, parent.Namespace, parent.Spec.Template)
+			if err := policy.Verifier.Verify(controllers.RetrieveValue(ctx, ResolvedImageStashKey).(string), keys, keychain); err != nil {
+				var missing *cnb.SignatureMissingError
+				if errors.As(err, &missing) {
+					parent.Status.MarkSignatureMissing(err.Error())
+				} else {
+					parent.Status.MarkSignatureInvalid(err.Error())
+				}
+				return err
+			}
+			parent.Status.MarkSignatureVerified()
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+// signaturePublicKeys parses the public keys in every value of the Secret
+func signaturePublicKeys(kubeClient kubernetes.Interface, key types.NamespacedName) ([]crypto.PublicKey, error) {
+	secret, err := kubeClient.CoreV1().Secrets(key.Namespace).Get(key.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read public keys: %w", err)
+	}
+	names := []string{}
+	for name := range secret.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keys := []crypto.PublicKey{}
+	for _, name := range names {
+		parsed, err := cnb.ParsePublicKeys(secret.Data[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %s in Secret %s: %w", name, key, err)
+		}
+		keys = append(keys, parsed...)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public keys found in Secret %s", key)
+	}
+	return keys, nil
+}
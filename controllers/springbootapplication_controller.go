@@ -18,10 +18,12 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/projectriff/system/pkg/controllers"
 	mononokev1alpha1 "github.com/spring-cloud-incubator/mononoke/api/v1alpha1"
 	"github.com/spring-cloud-incubator/mononoke/cnb"
@@ -31,51 +33,116 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // +kubebuilder:rbac:groups=apps.mononoke.local,resources=springbootapplications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps.mononoke.local,resources=springbootapplications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps.mononoke.local,resources=opinions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.mononoke.local,resources=opinionproviders,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets;serviceaccounts,verbs=get
 
 const ImageMetadataStashKey controllers.StashKey = "image-metadata"
 
-func SpringBootApplicationReconciler(c controllers.Config, registry cnb.Registry) *controllers.ParentReconciler {
+// ResolvedImageStashKey holds the image of the target container, pinned to
+// the digest the metadata was resolved from
+const ResolvedImageStashKey controllers.StashKey = "resolved-image"
+
+func SpringBootApplicationReconciler(c controllers.Config, metadataCache *cnb.MetadataCache, kubeClient kubernetes.Interface, signaturePolicy *SignaturePolicy, vulnerabilityPolicy *VulnerabilityPolicy) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("SpringBootApplication")
 
 	return &controllers.ParentReconciler{
 		Type: &mononokev1alpha1.SpringBootApplication{},
 		SubReconcilers: []controllers.SubReconciler{
-			SpringBootApplicationResolveImageMetadata(c, registry),
+			SpringBootApplicationResolveImage(c, metadataCache, kubeClient),
+			// signatures are verified before the image's layers are read
+			SpringBootApplicationVerifySignature(c, signaturePolicy, kubeClient),
+			SpringBootApplicationResolveImageMetadata(c, metadataCache, kubeClient),
+			SpringBootApplicationLicensePolicy(c),
+			SpringBootApplicationBuildpackPolicy(c),
+			SpringBootApplicationVulnerabilityPolicy(c, vulnerabilityPolicy),
 			SpringBootApplicationApplyOpinions(c),
 			SpringBootApplicationChildDeploymentReconciler(c),
+			SpringBootApplicationAcceptLatestImage(c, metadataCache, kubeClient),
+			SpringBootApplicationChildSBOMReconciler(c),
+		},
+
+		Config: c,
+	}
+}
+
+// SpringBootApplicationResolveImage resolves the digest of the target
+// container's image, without reading the image's config or layers
+func SpringBootApplicationResolveImage(c controllers.Config, metadataCache *cnb.MetadataCache, kubeClient kubernetes.Interface) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ResolveImage")
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) error {
+			_, containerIdx, err := FindTargetContainer(parent.Spec.TargetContainer, parent.Spec.Template)
+			if err != nil {
+				parent.Status.MarkTargetContainerNotFound(err.Error())
+				return err
+			}
+			ref := parent.Spec.Template.Spec.Containers[containerIdx].Image
+			keychain := NewImagePullKeychain(kubeClient, parent.Namespace, parent.Spec.Template)
+			digestedRef, err := metadataCache.ResolveDigest(ref, keychain)
+			if err != nil {
+				return markImageUnresolved(parent, fmt.Errorf("failed to resolve image %s: %w", ref, err))
+			}
+			controllers.StashValue(ctx, ResolvedImageStashKey, digestedRef)
+			return nil
 		},
 
 		Config: c,
 	}
 }
 
-func SpringBootApplicationResolveImageMetadata(c controllers.Config, registry cnb.Registry) controllers.SubReconciler {
+func SpringBootApplicationResolveImageMetadata(c controllers.Config, metadataCache *cnb.MetadataCache, kubeClient kubernetes.Interface) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ResolveImageMetadata")
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) error {
-			// TODO(scothis) be smarter about which container to use
-			containerIdx := 0
+			_, containerIdx, err := FindTargetContainer(parent.Spec.TargetContainer, parent.Spec.Template)
+			if err != nil {
+				parent.Status.MarkTargetContainerNotFound(err.Error())
+				return err
+			}
 			applicationContainer := &parent.Spec.Template.Spec.Containers[containerIdx]
 
-			ref := applicationContainer.Image
-			img, err := registry.GetImage(ref)
+			ref := controllers.RetrieveValue(ctx, ResolvedImageStashKey).(string)
+			keychain := NewImagePullKeychain(kubeClient, parent.Namespace, parent.Spec.Template)
+			digestedRef, md, err := metadataCache.Resolve(ref, keychain)
 			if err != nil {
-				return fmt.Errorf("failed to get image %s from registry: %w", ref, err)
+				return markImageUnresolved(parent, fmt.Errorf("failed to resolve cnb metadata for image %s: %w", ref, err))
 			}
-			md, err := cnb.ParseBuildMetadata(img)
-			if err != nil {
-				return fmt.Errorf("failed parse cnb metadata from image %s: %w", ref, err)
+			process := md.FindProcess(parent.Spec.ProcessType)
+			if parent.Spec.ProcessType == "" {
+				process = md.FindProcess(cnb.WebProcessType)
+			} else if process == nil {
+				err := fmt.Errorf("process type %q not found in image %s, expected one of %v", parent.Spec.ProcessType, ref, md.ProcessTypes())
+				parent.Status.MarkProcessTypeNotFound(err.Error())
+				return err
 			}
+			parent.Status.MarkImageResolved()
 			controllers.StashValue(ctx, ImageMetadataStashKey, md)
-			// TODO(scothis) update target container with digested image
-			// applicationContainer.Image = ...
+			// pin the target container to the image the metadata was resolved from
+			applicationContainer.Image = digestedRef
+			parent.Status.MetadataSource = string(md.Source)
+			parent.Status.ProcessType = ""
+			parent.Status.JavaVersion = ""
+			if jre := md.JavaRuntime(); jre != nil {
+				parent.Status.JavaVersion = jre.Version
+			}
+			if process != nil && len(applicationContainer.Command) == 0 && len(applicationContainer.Args) == 0 {
+				if command, args := process.ContainerCommand(md.Lifecycle.PlatformAPI); command != nil {
+					applicationContainer.Command, applicationContainer.Args = command, args
+					parent.Status.ProcessType = process.Type
+				}
+			}
 			return nil
 		},
 
@@ -83,23 +150,170 @@ func SpringBootApplicationResolveImageMetadata(c controllers.Config, registry cn
 	}
 }
 
+// markImageUnresolved reports why the image could not be resolved
+func markImageUnresolved(parent *mononokev1alpha1.SpringBootApplication, err error) error {
+	var missingCredentials *MissingCredentialsError
+	if errors.As(err, &missingCredentials) || cnb.IsUnauthorized(err) {
+		parent.Status.MarkImageCredentialsMissing(err.Error())
+	} else {
+		parent.Status.MarkImageInvalid(err.Error())
+	}
+	return err
+}
+
+// SpringBootApplicationAcceptLatestImage records the resolved image as the
+// latest image once it is rolled out to the deployment, along with how it
+// changed from the previous image. Policies that reject the image fail the
+// reconcile before the image is accepted, and changes are only reported once
+// for each image.
+func SpringBootApplicationAcceptLatestImage(c controllers.Config, metadataCache *cnb.MetadataCache, kubeClient kubernetes.Interface) controllers.SubReconciler {
+	c.Log = c.Log.WithName("AcceptLatestImage")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) error {
+			imageMetadata := controllers.RetrieveValue(ctx, ImageMetadataStashKey).(cnb.ImageMetadata)
+			latestImage := controllers.RetrieveValue(ctx, ResolvedImageStashKey).(string)
+			if previous := parent.Status.LatestImage; previous != "" && previous != latestImage {
+				keychain := NewImagePullKeychain(kubeClient, parent.Namespace, parent.Spec.Template)
+				reflectImageChanges(c, parent, previous, imageMetadata, metadataCache, keychain)
+			}
+			parent.Status.LatestImage = latestImage
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+// maxReportedChanges limits the number of changes named in an event or the
+// status
+const maxReportedChanges = 20
+
+// reflectImageChanges records events for the changes between the previous
+// image and the latest image, and summarizes the changes in the status
+func reflectImageChanges(c controllers.Config, parent *mononokev1alpha1.SpringBootApplication, previous string, md cnb.ImageMetadata, metadataCache *cnb.MetadataCache, keychain authn.Keychain) {
+	parent.Status.ImageChanges = nil
+	_, previousMetadata, err := metadataCache.Resolve(previous, keychain)
+	if err != nil {
+		// the previous image may have been deleted from the registry
+		c.Log.Info("unable to resolve cnb metadata for previous image", "image", previous, "error", err.Error())
+		return
+	}
+	diff := cnb.DiffImageMetadata(previousMetadata, md)
+	changes := &mononokev1alpha1.ImageChanges{
+		PreviousImage: previous,
+		Java:          diff.Filter(cnb.JavaChange).Summarize(maxReportedChanges),
+		Dependencies:  diff.Filter(cnb.DependencyChange).Summarize(maxReportedChanges),
+		Buildpacks:    diff.Filter(cnb.BuildpackChange).Summarize(maxReportedChanges),
+	}
+	if changes.Java != "" {
+		c.Recorder.Eventf(parent, corev1.EventTypeNormal, "JavaVersionChanged", "Java runtime changed: %s", changes.Java)
+	}
+	if changes.Dependencies != "" {
+		c.Recorder.Eventf(parent, corev1.EventTypeNormal, "DependenciesChanged", "Dependencies changed: %s", changes.Dependencies)
+	}
+	if changes.Buildpacks != "" {
+		c.Recorder.Eventf(parent, corev1.EventTypeNormal, "BuildpacksChanged", "Buildpacks changed: %s", changes.Buildpacks)
+	}
+	parent.Status.ImageChanges = changes
+}
+
 func SpringBootApplicationApplyOpinions(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ApplyOpinions")
 
 	return &controllers.SyncReconciler{
+		Setup: func(mgr ctrl.Manager, bldr *builder.Builder) error {
+			// opinions apply to every application
+			bldr.Watches(&source.Kind{Type: &mononokev1alpha1.Opinion{}}, enqueueSpringBootApplications(c))
+			bldr.Watches(&source.Kind{Type: &mononokev1alpha1.OpinionProvider{}}, enqueueSpringBootApplications(c))
+			return nil
+		},
 		Sync: func(ctx context.Context, parent *mononokev1alpha1.SpringBootApplication) error {
-			ctx = opinions.StashSpringApplicationProperties(ctx, parent.Spec.ApplicationProperties)
-			imageMetadata := controllers.RetrieveValue(ctx, ImageMetadataStashKey).(cnb.BuildMetadata)
+			imageMetadata := controllers.RetrieveValue(ctx, ImageMetadataStashKey).(cnb.ImageMetadata)
 			containerName, containerIdx, err := FindTargetContainer(parent.Spec.TargetContainer, parent.Spec.Template)
 			if err != nil {
 				return err
 			}
-			applied, err := opinions.SpringBoot.Apply(ctx, parent, containerIdx, imageMetadata)
+			opinionList := &mononokev1alpha1.OpinionList{}
+			if err := c.List(ctx, opinionList); err != nil {
+				return err
+			}
+			providerList := &mononokev1alpha1.OpinionProviderList{}
+			if err := c.List(ctx, providerList); err != nil {
+				return err
+			}
+			applicableOpinions, invalidOpinions := mononokev1alpha1.Opinions(opinions.Default, opinionList, providerList)
+			for _, invalid := range invalidOpinions {
+				// invalid opinions are skipped, the problem is reported on the opinion
+				c.Recorder.Event(invalid.Object, corev1.EventTypeWarning, "InvalidOpinion", invalid.Message)
+			}
+			if parent.Spec.ApplicationProperties == nil {
+				parent.Spec.ApplicationProperties = map[string]string{}
+			}
+			// opinions see the properties packaged in the image, overridden by the spec
+			activeProfiles := activeApplicationProfiles(parent, containerIdx, imageMetadata)
+			for _, profile := range append([]string{""}, activeProfiles...) {
+				if message, ok := imageMetadata.InvalidApplicationProperties[profile]; ok {
+					parent.Status.MarkOpinionsFailed("InvalidApplicationProperties", message)
+					return errors.New(message)
+				}
+			}
+			for profile, message := range imageMetadata.InvalidApplicationProperties {
+				c.Log.Info("ignoring configuration file of inactive profile", "profile", profile, "error", message)
+			}
+			packagedProperties := imageMetadata.ApplicationProperties.Resolve(activeProfiles)
+			applicationProperties := opinions.SpringApplicationProperties{}
+			for key, value := range packagedProperties {
+				applicationProperties[key] = value
+			}
+			for key, value := range parent.Spec.ApplicationProperties {
+				applicationProperties[key] = value
+			}
+			ctx = opinions.StashSpringApplicationProperties(ctx, applicationProperties)
+			options := opinions.Options{}
+			if parent.Spec.Opinions != nil {
+				options.Enabled = parent.Spec.Opinions.Enabled
+				options.Disabled = parent.Spec.Opinions.Disabled
+				options.Explain = parent.Spec.Opinions.Explain
+			}
+			result, err := applicableOpinions.Apply(ctx, parent, containerIdx, imageMetadata, options)
 			if err != nil {
+				reason := "OpinionFailed"
+				var opinionErr *opinions.Error
+				if errors.As(err, &opinionErr) {
+					reason = opinionErr.Reason
+				}
+				parent.Status.MarkOpinionsFailed(reason, err.Error())
 				return err
 			}
+			parent.Status.MarkOpinionsApplied()
+			// only properties that differ from the packaged properties need to be set on the container
+			for key, value := range applicationProperties {
+				if packaged, ok := packagedProperties[key]; !ok || packaged != value {
+					parent.Spec.ApplicationProperties[key] = value
+				}
+			}
 			parent.Status.TargetContainer = containerName
-			parent.Status.AppliedOpinions = applied
+			parent.Status.AppliedOpinions = result.Applied
+			parent.Status.SkippedOpinions = nil
+			for _, o := range result.Skipped {
+				parent.Status.SkippedOpinions = append(parent.Status.SkippedOpinions, mononokev1alpha1.SkippedOpinion{
+					ID:      o.Id,
+					Reason:  o.Reason,
+					Message: o.Message,
+				})
+			}
+			parent.Status.OpinionDecisions = nil
+			for _, d := range result.Decisions {
+				parent.Status.OpinionDecisions = append(parent.Status.OpinionDecisions, mononokev1alpha1.OpinionDecision{
+					ID:                  d.Id,
+					Applied:             d.Applied,
+					Reason:              d.Reason,
+					Dependencies:        d.Dependencies,
+					DefaultedProperties: d.DefaultedProperties,
+					Patch:               d.Patch,
+				})
+			}
 
 			return nil
 		},
@@ -108,6 +322,23 @@ func SpringBootApplicationApplyOpinions(c controllers.Config) controllers.SubRec
 	}
 }
 
+// activeApplicationProfiles returns the profiles active in the spec, the
+// target container's environment or the packaged properties, in order of
+// precedence.
+func activeApplicationProfiles(parent *mononokev1alpha1.SpringBootApplication, containerIdx int, imageMetadata cnb.ImageMetadata) []string {
+	const activeProfilesKey = "spring.profiles.active"
+	activeProfiles, ok := parent.Spec.ApplicationProperties[activeProfilesKey]
+	if !ok {
+		if env := findEnvVar(parent.Spec.Template.Spec.Containers[containerIdx], "SPRING_PROFILES_ACTIVE"); env != nil {
+			activeProfiles, ok = env.Value, true
+		}
+	}
+	if !ok {
+		activeProfiles = imageMetadata.ApplicationProperties.Resolve(nil)[activeProfilesKey]
+	}
+	return cnb.ActiveProfiles(activeProfiles)
+}
+
 func SpringBootApplicationChildDeploymentReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildDeployment")
 
@@ -202,9 +433,10 @@ func SpringBootApplicationChildDeploymentReconciler(c controllers.Config) contro
 }
 
 func findEnvVar(container corev1.Container, name string) *corev1.EnvVar {
-	for _, e := range container.Env {
-		if e.Name == name {
-			return &e
+	for i := range container.Env {
+		// the env slice is shared with the container the copy was made from
+		if container.Env[i].Name == name {
+			return &container.Env[i]
 		}
 	}
 	return nil
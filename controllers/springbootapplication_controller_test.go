@@ -0,0 +1,252 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/projectriff/system/pkg/controllers"
+	mononokev1alpha1 "github.com/spring-cloud-incubator/mononoke/api/v1alpha1"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	"github.com/spring-cloud-incubator/mononoke/opinions"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// applyOpinions runs the ApplyOpinions reconciler for the application,
+// returning the events it recorded
+func applyOpinions(t *testing.T, parent *mononokev1alpha1.SpringBootApplication, imageMetadata cnb.ImageMetadata, objects ...runtime.Object) ([]string, error) {
+	scheme := runtime.NewScheme()
+	if err := mononokev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	recorder := record.NewFakeRecorder(10)
+	reconciler := SpringBootApplicationApplyOpinions(controllers.Config{
+		Client:   fake.NewFakeClientWithScheme(scheme, objects...),
+		Recorder: recorder,
+		Log:      log.NullLogger{},
+		Scheme:   scheme,
+	})
+
+	ctx := controllers.WithStash(context.Background())
+	controllers.StashValue(ctx, ImageMetadataStashKey, imageMetadata)
+	_, err := reconciler.Reconcile(ctx, parent)
+
+	events := []string{}
+	for len(recorder.Events) != 0 {
+		events = append(events, <-recorder.Events)
+	}
+	return events, err
+}
+
+func springBootApplication(selection *mononokev1alpha1.OpinionSelection) *mononokev1alpha1.SpringBootApplication {
+	parent := &mononokev1alpha1.SpringBootApplication{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "petclinic"},
+		Spec: mononokev1alpha1.SpringBootApplicationSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app"}},
+				},
+			},
+			Opinions: selection,
+		},
+	}
+	parent.Default()
+	return parent
+}
+
+func TestApplyOpinions_Decisions(t *testing.T) {
+	parent := springBootApplication(&mononokev1alpha1.OpinionSelection{
+		Disabled: []string{"nodejs"},
+		Explain:  true,
+	})
+	// decisions of a previous reconcile are replaced
+	parent.Status.OpinionDecisions = []mononokev1alpha1.OpinionDecision{{ID: "stale"}}
+	opinion := &mononokev1alpha1.Opinion{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-label"},
+		Spec: mononokev1alpha1.OpinionSpec{
+			Order: 10,
+			Effects: mononokev1alpha1.OpinionEffects{
+				Labels: map[string]string{"team": "pets"},
+			},
+		},
+	}
+
+	events, err := applyOpinions(t, parent, cnb.ImageMetadata{}, opinion)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("unexpected events %v", events)
+	}
+	if diff := cmp.Diff([]string{"team-label"}, parent.Status.AppliedOpinions); diff != "" {
+		t.Errorf("applied opinions (-expected, +actual) = %v", diff)
+	}
+	if parent.Status.GetCondition(mononokev1alpha1.SpringBootApplicationConditionOpinionsApplied).Status != corev1.ConditionTrue {
+		t.Errorf("expected opinions to be applied")
+	}
+
+	decisions := map[string]mononokev1alpha1.OpinionDecision{}
+	for _, d := range parent.Status.OpinionDecisions {
+		decisions[d.ID] = d
+	}
+	if len(decisions) != len(opinions.Default)+1 {
+		t.Errorf("expected a decision for every opinion, got %d", len(decisions))
+	}
+	expected := []mononokev1alpha1.OpinionDecision{
+		{ID: "nodejs", Reason: opinions.DisabledReason},
+		{ID: "nodejs-port", Reason: opinions.PrerequisiteSkippedReason},
+		{ID: "spring-boot", Reason: opinions.NotApplicableReason},
+		{ID: "team-label", Applied: true, Patch: `[{"op":"add","path":"/metadata/labels","value":{"team":"pets"}}]`},
+	}
+	for _, e := range expected {
+		if diff := cmp.Diff(e, decisions[e.ID]); diff != "" {
+			t.Errorf("decision %s (-expected, +actual) = %v", e.ID, diff)
+		}
+	}
+
+	skipped := map[string]string{}
+	for _, s := range parent.Status.SkippedOpinions {
+		skipped[s.ID] = s.Reason
+	}
+	if skipped["nodejs"] != opinions.DisabledReason || skipped["nodejs-port"] != opinions.PrerequisiteSkippedReason {
+		t.Errorf("unexpected skipped opinions %v", parent.Status.SkippedOpinions)
+	}
+	if _, ok := skipped["spring-boot"]; ok {
+		t.Errorf("opinions that are not applicable are not skipped")
+	}
+}
+
+func TestApplyOpinions_NotExplained(t *testing.T) {
+	parent := springBootApplication(nil)
+	parent.Status.OpinionDecisions = []mononokev1alpha1.OpinionDecision{{ID: "stale"}}
+
+	if _, err := applyOpinions(t, parent, cnb.ImageMetadata{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if parent.Status.OpinionDecisions != nil {
+		t.Errorf("expected no decisions, got %v", parent.Status.OpinionDecisions)
+	}
+	if parent.Status.SkippedOpinions != nil {
+		t.Errorf("expected no skipped opinions, got %v", parent.Status.SkippedOpinions)
+	}
+}
+
+func TestApplyOpinions_InvalidOpinion(t *testing.T) {
+	parent := springBootApplication(nil)
+	invalid := &mononokev1alpha1.Opinion{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+		Spec: mononokev1alpha1.OpinionSpec{
+			When: []mononokev1alpha1.OpinionCondition{{}},
+		},
+	}
+
+	events, err := applyOpinions(t, parent, cnb.ImageMetadata{}, invalid)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning InvalidOpinion ") {
+		t.Errorf("expected an InvalidOpinion event, got %v", events)
+	}
+	if parent.Status.GetCondition(mononokev1alpha1.SpringBootApplicationConditionOpinionsApplied).Status != corev1.ConditionTrue {
+		t.Errorf("expected the other opinions to be applied")
+	}
+}
+
+func TestApplyOpinions_UnknownOpinion(t *testing.T) {
+	parent := springBootApplication(&mononokev1alpha1.OpinionSelection{
+		Enabled: []string{"not-an-opinion"},
+	})
+
+	if _, err := applyOpinions(t, parent, cnb.ImageMetadata{}); err == nil {
+		t.Fatalf("expected error")
+	}
+	condition := parent.Status.GetCondition(mononokev1alpha1.SpringBootApplicationConditionOpinionsApplied)
+	if condition.Status != corev1.ConditionFalse || condition.Reason != "UnknownOpinion" {
+		t.Errorf("expected UnknownOpinion condition, got %v", condition)
+	}
+}
+
+func TestApplyOpinions_UnknownDisabledOpinion(t *testing.T) {
+	parent := springBootApplication(&mononokev1alpha1.OpinionSelection{
+		Disabled: []string{"deleted-opinion"},
+	})
+
+	if _, err := applyOpinions(t, parent, cnb.ImageMetadata{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff([]mononokev1alpha1.SkippedOpinion{{
+		ID:      "deleted-opinion",
+		Reason:  opinions.UnknownOpinionReason,
+		Message: "disabled opinion is unknown",
+	}}, parent.Status.SkippedOpinions); diff != "" {
+		t.Errorf("skipped opinions (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestApplyOpinions_UnorderableOpinion(t *testing.T) {
+	parent := springBootApplication(nil)
+	objects := []runtime.Object{
+		&mononokev1alpha1.Opinion{
+			ObjectMeta: metav1.ObjectMeta{Name: "chicken"},
+			Spec:       mononokev1alpha1.OpinionSpec{DependsOn: []string{"egg"}},
+		},
+		&mononokev1alpha1.Opinion{
+			ObjectMeta: metav1.ObjectMeta{Name: "egg"},
+			Spec:       mononokev1alpha1.OpinionSpec{DependsOn: []string{"chicken"}},
+		},
+		&mononokev1alpha1.Opinion{
+			ObjectMeta: metav1.ObjectMeta{Name: "orphan"},
+			Spec:       mononokev1alpha1.OpinionSpec{After: []string{"deleted"}},
+		},
+		&mononokev1alpha1.Opinion{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-label"},
+			Spec: mononokev1alpha1.OpinionSpec{
+				Effects: mononokev1alpha1.OpinionEffects{
+					Labels: map[string]string{"team": "pets"},
+				},
+			},
+		},
+	}
+
+	// the misconfigured opinions don't fail the application
+	events, err := applyOpinions(t, parent, cnb.ImageMetadata{}, objects...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff([]string{"team-label"}, parent.Status.AppliedOpinions); diff != "" {
+		t.Errorf("applied opinions (-expected, +actual) = %v", diff)
+	}
+	if parent.Status.GetCondition(mononokev1alpha1.SpringBootApplicationConditionOpinionsApplied).Status != corev1.ConditionTrue {
+		t.Errorf("expected opinions to be applied")
+	}
+	if len(events) != 3 {
+		t.Errorf("expected an InvalidOpinion event for each misconfigured opinion, got %v", events)
+	}
+	for _, event := range events {
+		if !strings.HasPrefix(event, "Warning InvalidOpinion ") {
+			t.Errorf("expected an InvalidOpinion event, got %q", event)
+		}
+	}
+}
@@ -4,10 +4,15 @@ go 1.13
 
 require (
 	github.com/Masterminds/semver v1.5.0
+	github.com/evanphx/json-patch v4.5.0+incompatible
 	github.com/google/go-cmp v0.4.0
 	github.com/google/go-containerregistry v0.0.0-20200304201134-fcc8ea80e26f
+	github.com/hashicorp/golang-lru v0.5.3
 	github.com/projectriff/system v0.5.0
+	github.com/prometheus/client_golang v1.0.0
 	golang.org/x/tools v0.0.0-20200306191617-51e69f71924f // indirect
+	gomodules.xyz/jsonpatch/v2 v2.0.1
+	gopkg.in/yaml.v2 v2.2.8
 	k8s.io/api v0.17.3
 	k8s.io/apimachinery v0.17.3
 	k8s.io/client-go v0.17.3
@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/authn/k8schain"
@@ -26,10 +27,13 @@ import (
 	"github.com/projectriff/system/pkg/tracker"
 	"github.com/spring-cloud-incubator/mononoke/cnb"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	appsv1alpha1 "github.com/spring-cloud-incubator/mononoke/api/v1alpha1"
 	mononokecontrollers "github.com/spring-cloud-incubator/mononoke/controllers"
@@ -52,10 +56,35 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var imageMetadataCacheSize int
+	var imageTagTTL time.Duration
+	var imageLayout string
+	var imageTarball string
+	var signaturePublicKeys string
+	var advisoryDatabase string
+	var advisoryConfigMap string
+	var vulnerabilitySeverityThreshold string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.IntVar(&imageMetadataCacheSize, "image-metadata-cache-size", 1000, "The number of images to cache parsed metadata for.")
+	flag.DurationVar(&imageTagTTL, "image-tag-ttl", 5*time.Minute,
+		"How long the digest resolved for an image tag is cached. "+
+			"Tags are re-resolved against the registry once expired.")
+	flag.StringVar(&imageLayout, "image-layout", "",
+		"Read images from an OCI image layout directory rather than from remote registries.")
+	flag.StringVar(&imageTarball, "image-tarball", "",
+		"Read images from a tarball created by docker save rather than from remote registries.")
+	flag.StringVar(&signaturePublicKeys, "signature-public-keys", "",
+		"Require images are signed by one of the PEM encoded public keys in a Secret, as namespace/name.")
+	flag.StringVar(&advisoryDatabase, "advisory-database", "",
+		"Match application dependencies against the OSV advisories in a directory.")
+	flag.StringVar(&advisoryConfigMap, "advisory-configmap", "",
+		"Match application dependencies against the OSV advisories in a ConfigMap, as namespace/name.")
+	flag.StringVar(&vulnerabilitySeverityThreshold, "vulnerability-severity-threshold", "",
+		"Block the rollout of applications with a vulnerability of this severity or more, one of CRITICAL, HIGH, MEDIUM, LOW or UNKNOWN. "+
+			"Vulnerabilities are only reported when empty.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -73,12 +102,63 @@ func main() {
 		os.Exit(1)
 	}
 
-	kc, err := k8schain.NewNoClient()
-	if err != nil {
-		setupLog.Error(err, "unable to create k8schain")
-		os.Exit(1)
+	var registry cnb.Registry
+	switch {
+	case imageLayout != "":
+		registry = &cnb.LayoutRegistry{Path: imageLayout}
+	case imageTarball != "":
+		registry = &cnb.TarballRegistry{Path: imageTarball}
+	default:
+		kc, err := k8schain.NewNoClient()
+		if err != nil {
+			setupLog.Error(err, "unable to create k8schain")
+			os.Exit(1)
+		}
+		registry = &cnb.RemoteRegistry{Keychain: kc}
+	}
+
+	var signaturePolicy *mononokecontrollers.SignaturePolicy
+	if signaturePublicKeys != "" {
+		parts := strings.SplitN(signaturePublicKeys, "/", 2)
+		if len(parts) != 2 {
+			setupLog.Error(nil, "invalid signature public keys Secret, expected namespace/name", "secret", signaturePublicKeys)
+			os.Exit(1)
+		}
+		signaturePolicy = &mononokecontrollers.SignaturePolicy{
+			Verifier:   cnb.NewSignatureVerifier(registry, imageMetadataCacheSize),
+			PublicKeys: types.NamespacedName{Namespace: parts[0], Name: parts[1]},
+		}
 	}
 
+	var vulnerabilityPolicy *mononokecontrollers.VulnerabilityPolicy
+	switch {
+	case advisoryDatabase != "":
+		vulnerabilityPolicy = &mononokecontrollers.VulnerabilityPolicy{
+			Advisories: &mononokecontrollers.DirectoryAdvisorySource{Path: advisoryDatabase},
+		}
+	case advisoryConfigMap != "":
+		parts := strings.SplitN(advisoryConfigMap, "/", 2)
+		if len(parts) != 2 {
+			setupLog.Error(nil, "invalid advisory ConfigMap, expected namespace/name", "configmap", advisoryConfigMap)
+			os.Exit(1)
+		}
+		vulnerabilityPolicy = &mononokecontrollers.VulnerabilityPolicy{
+			Advisories: &mononokecontrollers.ConfigMapAdvisorySource{
+				ConfigMap: types.NamespacedName{Namespace: parts[0], Name: parts[1]},
+			},
+		}
+	}
+	if vulnerabilityPolicy != nil && vulnerabilitySeverityThreshold != "" {
+		vulnerabilityPolicy.Threshold, err = cnb.ParseSeverity(vulnerabilitySeverityThreshold)
+		if err != nil {
+			setupLog.Error(err, "invalid vulnerability severity threshold")
+			os.Exit(1)
+		}
+	}
+
+	metadataCache := cnb.NewMetadataCache(registry, imageMetadataCacheSize, imageTagTTL)
+	metrics.Registry.MustRegister(metadataCache.Collectors()...)
+
 	if err = mononokecontrollers.SpringBootApplicationReconciler(
 		controllers.Config{
 			Client:    mgr.GetClient(),
@@ -88,7 +168,10 @@ func main() {
 			Log:       ctrl.Log.WithName("controllers").WithName("SpringBootApplication"),
 			Scheme:    mgr.GetScheme(),
 		},
-		cnb.Registry{Keychain: kc},
+		metadataCache,
+		kubernetes.NewForConfigOrDie(mgr.GetConfig()),
+		signaturePolicy,
+		vulnerabilityPolicy,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SpringBootApplication")
 		os.Exit(1)
@@ -0,0 +1,109 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	"gomodules.xyz/jsonpatch/v2"
+)
+
+// Decision records why an opinion was or was not applied, and how an applied
+// opinion changed the resource
+type Decision struct {
+	Id string
+	// Applied is true when the opinion was applied
+	Applied bool
+	// Reason the opinion was not applied
+	Reason string
+	// Dependencies of the image that made the opinion applicable
+	Dependencies []string
+	// DefaultedProperties are the application properties the opinion set
+	DefaultedProperties map[string]string
+	// Patch is the JSON patch of the changes the opinion made to the pod
+	// template
+	Patch string
+}
+
+// DependencyOpinion is an opinion that applies to images with particular
+// dependencies
+type DependencyOpinion interface {
+	Opinion
+	// MatchedDependencies returns the dependencies of the image the opinion
+	// applies for
+	MatchedDependencies(imageMetadata cnb.ImageMetadata) []cnb.Dependency
+}
+
+// explain applies the opinion, recording the changes it makes
+func explain(ctx context.Context, o Opinion, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) (*Decision, error) {
+	decision := &Decision{
+		Id:      o.GetId(),
+		Applied: true,
+	}
+	if d, ok := o.(DependencyOpinion); ok {
+		for _, dependency := range d.MatchedDependencies(imageMetadata) {
+			decision.Dependencies = append(decision.Dependencies, fmt.Sprintf("%s %s", dependency.Name, dependency.Version))
+		}
+	}
+
+	applicationProperties := GetSpringApplicationProperties(ctx)
+	previousProperties := map[string]string{}
+	for key, value := range applicationProperties {
+		previousProperties[key] = value
+	}
+	previousTemplate, err := json.Marshal(target.PodTemplate())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.Apply(ctx, target, containerIdx, imageMetadata); err != nil {
+		return nil, err
+	}
+
+	for key, value := range applicationProperties {
+		if previous, ok := previousProperties[key]; !ok || previous != value {
+			if decision.DefaultedProperties == nil {
+				decision.DefaultedProperties = map[string]string{}
+			}
+			decision.DefaultedProperties[key] = value
+		}
+	}
+	template, err := json.Marshal(target.PodTemplate())
+	if err != nil {
+		return nil, err
+	}
+	patch, err := jsonpatch.CreatePatch(previousTemplate, template)
+	if err != nil {
+		return nil, err
+	}
+	if len(patch) != 0 {
+		// operations are created from maps, order them so the patch is stable
+		sort.SliceStable(patch, func(i, j int) bool {
+			return patch[i].Path < patch[j].Path
+		})
+		content, err := json.Marshal(patch)
+		if err != nil {
+			return nil, err
+		}
+		decision.Patch = string(content)
+	}
+	return decision, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestOpinions_Apply_Explain(t *testing.T) {
+	os := Opinions{
+		&BasicOpinion{
+			Id:           "framework",
+			Dependencies: []string{"framework-core"},
+			ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, metadata cnb.ImageMetadata) bool {
+				bootMetadata := NewSpringBootBOMMetadata(metadata)
+				return bootMetadata.HasDependency("framework-core")
+			},
+			ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error {
+				SetLabel(target, "apps.mononoke.local/framework", "framework")
+				return nil
+			},
+		},
+		&BasicOpinion{
+			Id:           "framework-port",
+			DependsOn:    []string{"framework"},
+			Dependencies: []string{"framework-http"},
+			ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error {
+				GetSpringApplicationProperties(ctx).Default("framework.port", "8080")
+				addContainerPort(target, containerIdx, 8080)
+				return nil
+			},
+		},
+		&BasicOpinion{
+			Id: "unrelated",
+			ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, metadata cnb.ImageMetadata) bool {
+				return false
+			},
+		},
+		&BasicOpinion{
+			Id:        "unrelated-extension",
+			DependsOn: []string{"unrelated"},
+		},
+		&BasicOpinion{
+			Id: "disabled",
+		},
+	}
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+	target := newTestResource()
+	imageMetadata := libraryMetadata("framework-core@1.0.0", "framework-http@1.0.1", "other@2.0.0")
+
+	result, err := os.Apply(ctx, target, 0, imageMetadata, Options{Disabled: []string{"disabled"}, Explain: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []Decision{{
+		Id:           "framework",
+		Applied:      true,
+		Dependencies: []string{"framework-core 1.0.0"},
+		Patch:        `[{"op":"add","path":"/metadata/labels","value":{"apps.mononoke.local/framework":"framework"}}]`,
+	}, {
+		Id:                  "framework-port",
+		Applied:             true,
+		Dependencies:        []string{"framework-http 1.0.1"},
+		DefaultedProperties: map[string]string{"framework.port": "8080"},
+		Patch:               `[{"op":"add","path":"/spec/containers/0/ports","value":[{"containerPort":8080,"protocol":"TCP"}]}]`,
+	}, {
+		Id:     "unrelated",
+		Reason: NotApplicableReason,
+	}, {
+		Id:     "unrelated-extension",
+		Reason: PrerequisiteNotAppliedReason,
+	}, {
+		Id:     "disabled",
+		Reason: DisabledReason,
+	}}
+	if diff := cmp.Diff(expected, result.Decisions); diff != "" {
+		t.Errorf("decisions (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestOpinions_Apply_ExplainUnchanged(t *testing.T) {
+	os := Opinions{
+		&BasicOpinion{
+			Id: "noop",
+			ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error {
+				// defaulting a property to its current value is not a change
+				GetSpringApplicationProperties(ctx).Default("server.port", "8080")
+				return nil
+			},
+		},
+	}
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{"server.port": "8080"})
+	target := newTestResource(corev1.Container{Name: "app", Image: "registry.example/app"})
+
+	result, err := os.Apply(ctx, target, 0, cnb.ImageMetadata{}, Options{Explain: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []Decision{{Id: "noop", Applied: true}}
+	if diff := cmp.Diff(expected, result.Decisions); diff != "" {
+		t.Errorf("decisions (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestOpinions_Apply_NotExplained(t *testing.T) {
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+	target := newTestResource()
+	imageMetadata := libraryMetadata("quarkus-core@1.13.0.Final")
+
+	result, err := Quarkus.Apply(ctx, target, 0, imageMetadata, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Decisions != nil {
+		t.Errorf("expected no decisions, got %v", result.Decisions)
+	}
+}
@@ -0,0 +1,377 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const mebibyte = 1024 * 1024
+
+// defaults of the memory calculator, see
+// https://github.com/cloudfoundry/java-buildpack-memory-calculator
+const (
+	// portion of the available classes the JVM is expected to load
+	loadedClassRatio     = 0.35
+	defaultThreadCount   = 250
+	threadStackSize      = 1 * mebibyte
+	codeCacheSize        = 240 * mebibyte
+	directMemorySize     = 10 * mebibyte
+	metaspacePerClass    = 5800
+	metaspaceOverhead    = 14000000
+	nonRequestThreads    = 50
+	minimumHeapSize      = 128 * mebibyte
+	defaultMemoryLimit   = 1024 * mebibyte
+	memoryLimitIncrement = 256 * mebibyte
+)
+
+// approximate number of classes of the Java runtime since a major version,
+// newest first. The runtime is not part of the application's layers so its
+// classes are not counted, and the classes of a JDK's tools are not loaded by
+// applications.
+var jvmClassCounts = []struct {
+	major int
+	count int
+}{
+	{major: 17, count: 26000},
+	{major: 11, count: 24000},
+	{major: 9, count: 23000},
+	{major: 0, count: 19000},
+}
+
+var JVM = Opinions{
+	&BasicOpinion{
+		Id:           "jvm-memory-calculator",
+		Dependencies: []string{"memory-calculator"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			return imageMetadata.FindDependency("memory-calculator", cnb.RuntimeDependency) != nil
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			calculator := imageMetadata.FindDependency("memory-calculator", cnb.RuntimeDependency)
+			env := newMemoryCalculatorEnv(calculator.Buildpack)
+			applicationProperties := GetSpringApplicationProperties(ctx)
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+
+			// the calculator reads its inputs from the environment at launch,
+			// only inputs that differ from its defaults are set
+			threadCount := defaultThreadCount
+			for _, key := range []string{"server.tomcat.threads.max", "server.tomcat.max-threads"} {
+				if maxThreads, err := strconv.Atoi(applicationProperties[key]); err == nil {
+					threadCount = maxThreads + nonRequestThreads
+					break
+				}
+			}
+			if e := findEnv(c, env.ThreadCount); e == nil && threadCount != defaultThreadCount {
+				c.Env = append(c.Env, corev1.EnvVar{Name: env.ThreadCount, Value: strconv.Itoa(threadCount)})
+			}
+			// the calculator counts the classes itself when unset
+			loadedClassCount := int(math.Ceil(loadedClassRatio * float64(jvmClassCount(imageMetadata)+imageMetadata.ClassCount)))
+			headRoom := 0
+			for _, input := range []struct {
+				name  string
+				value *int
+			}{
+				{name: env.ThreadCount, value: &threadCount},
+				{name: env.LoadedClassCount, value: &loadedClassCount},
+				{name: env.HeadRoom, value: &headRoom},
+			} {
+				e := findEnv(c, input.name)
+				if e == nil {
+					continue
+				}
+				value, err := strconv.Atoi(e.Value)
+				if err != nil {
+					return &Error{
+						Reason:  "InvalidMemoryCalculatorInput",
+						Message: fmt.Sprintf("environment variable %s must be an integer, found %q", input.name, e.Value),
+					}
+				}
+				*input.value = value
+			}
+			if headRoom < 0 || headRoom >= 100 {
+				return &Error{
+					Reason:  "InvalidMemoryCalculatorInput",
+					Message: fmt.Sprintf("environment variable %s must be a percentage between 0 and 99, found %d", env.HeadRoom, headRoom),
+				}
+			}
+
+			memory := newJVMMemory(threadCount, loadedClassCount, headRoom)
+			minimum := memory.MinimumLimit()
+			if c.Resources.Limits == nil {
+				c.Resources.Limits = corev1.ResourceList{}
+			}
+			if c.Resources.Requests == nil {
+				c.Resources.Requests = corev1.ResourceList{}
+			}
+			limit, ok := c.Resources.Limits[corev1.ResourceMemory]
+			if !ok {
+				// without a limit the calculator sizes the JVM for the node's memory
+				limitBytes := int64(defaultMemoryLimit)
+				if minimum > limitBytes {
+					limitBytes = roundUp(minimum, memoryLimitIncrement)
+				}
+				// the limit may not be less than the request
+				if request, ok := c.Resources.Requests[corev1.ResourceMemory]; ok && request.Value() > limitBytes {
+					limitBytes = request.Value()
+				}
+				limit = *resource.NewQuantity(limitBytes, resource.BinarySI)
+				c.Resources.Limits[corev1.ResourceMemory] = limit
+			} else if limit.Value() < minimum {
+				return &Error{
+					Reason: "InsufficientMemory",
+					Message: fmt.Sprintf(
+						"memory limit %s is less than the %dMi the JVM needs for %d threads and %d loaded classes (%s), increase the memory limit or reduce the thread count with %s",
+						limit.String(), roundUp(minimum, mebibyte)/mebibyte, threadCount, loadedClassCount, memory, env.ThreadCount,
+					),
+				}
+			}
+			if _, ok := c.Resources.Requests[corev1.ResourceMemory]; !ok {
+				// the calculator commits to the whole limit, request it up front
+				// unless the request is set
+				c.Resources.Requests[corev1.ResourceMemory] = limit
+			}
+
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id: "jvm-version",
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			return imageMetadata.JavaRuntime() != nil
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			SetAnnotation(target, "apps.mononoke.local/java-version", imageMetadata.JavaRuntime().Version)
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id: "jvm-container-support",
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			version, ok := javaVersion(imageMetadata)
+			// cgroup memory limits are supported since 8u131
+			return ok && version.AtLeast(8, 131)
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			version, _ := javaVersion(imageMetadata)
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+			if hasJavaOpt(c, "-XX:+UseContainerSupport", "-XX:-UseContainerSupport", "-XX:+UseCGroupMemoryLimitForHeap") {
+				// container support is already configured
+				return nil
+			}
+			if version.AtLeast(10, 0) || (version.Major == 8 && version.AtLeast(8, 191)) {
+				addJavaOpts(c, "-XX:+UseContainerSupport")
+			} else {
+				// experimental before 8u191 and 10
+				addJavaOpts(c, "-XX:+UnlockExperimentalVMOptions", "-XX:+UseCGroupMemoryLimitForHeap")
+			}
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id: "jvm-active-processor-count",
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			version, ok := javaVersion(imageMetadata)
+			// the flag is supported since 8u191 and 10
+			return ok && (version.AtLeast(10, 0) || (version.Major == 8 && version.AtLeast(8, 191)))
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+			cpu, ok := c.Resources.Limits[corev1.ResourceCPU]
+			if !ok || cpu.MilliValue()%1000 == 0 || hasJavaOpt(c, "-XX:ActiveProcessorCount=") {
+				return nil
+			}
+			addJavaOpts(c, fmt.Sprintf("-XX:ActiveProcessorCount=%d", processorCount(cpu)))
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id: "jvm-gc",
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			_, ok := javaVersion(imageMetadata)
+			return ok
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+			cpu, ok := c.Resources.Limits[corev1.ResourceCPU]
+			if !ok || matchesJavaOpts(c, gcPattern) {
+				return nil
+			}
+			if processorCount(cpu) < 2 {
+				// concurrent collectors cost more than they save on a single processor
+				addJavaOpts(c, "-XX:+UseSerialGC")
+			} else {
+				addJavaOpts(c, "-XX:+UseG1GC")
+			}
+			return nil
+		},
+	},
+}
+
+var gcPattern = regexp.MustCompile(`-XX:\+Use[A-Za-z0-9]+GC\b`)
+
+func javaVersion(imageMetadata cnb.ImageMetadata) (cnb.JavaVersion, bool) {
+	jre := imageMetadata.JavaRuntime()
+	if jre == nil {
+		return cnb.JavaVersion{}, false
+	}
+	version, err := cnb.ParseJavaVersion(jre.Version)
+	if err != nil {
+		return cnb.JavaVersion{}, false
+	}
+	return version, true
+}
+
+// jvmClassCount estimates the number of classes of the image's Java runtime
+// from its version, assuming the newest runtime when the version is unknown
+func jvmClassCount(imageMetadata cnb.ImageMetadata) int {
+	version, ok := javaVersion(imageMetadata)
+	if !ok {
+		return jvmClassCounts[0].count
+	}
+	for _, c := range jvmClassCounts {
+		if version.Major >= c.major {
+			return c.count
+		}
+	}
+	return jvmClassCounts[len(jvmClassCounts)-1].count
+}
+
+// processorCount is the number of processors the JVM sees for the CPU limit
+func processorCount(cpu resource.Quantity) int64 {
+	count := (cpu.MilliValue() + 999) / 1000
+	if count < 1 {
+		return 1
+	}
+	return count
+}
+
+// addJavaOpts appends the options to the JAVA_OPTS env var of the container
+func addJavaOpts(c *corev1.Container, opts ...string) {
+	javaOpts := findEnv(c, "JAVA_OPTS")
+	if javaOpts == nil {
+		c.Env = append(c.Env, corev1.EnvVar{Name: "JAVA_OPTS"})
+		javaOpts = &c.Env[len(c.Env)-1]
+	}
+	javaOpts.Value = strings.TrimSpace(javaOpts.Value + " " + strings.Join(opts, " "))
+}
+
+// hasJavaOpt returns true if JAVA_OPTS has an option with one of the prefixes
+func hasJavaOpt(c *corev1.Container, prefixes ...string) bool {
+	javaOpts := findEnv(c, "JAVA_OPTS")
+	if javaOpts == nil {
+		return false
+	}
+	for _, opt := range strings.Fields(javaOpts.Value) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(opt, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesJavaOpts returns true if the pattern matches the JAVA_OPTS env var of
+// the container
+func matchesJavaOpts(c *corev1.Container, pattern *regexp.Regexp) bool {
+	javaOpts := findEnv(c, "JAVA_OPTS")
+	return javaOpts != nil && pattern.MatchString(javaOpts.Value)
+}
+
+// memoryCalculatorEnv names the environment variables the memory calculator
+// reads at launch, which differ between buildpack families
+type memoryCalculatorEnv struct {
+	ThreadCount      string
+	LoadedClassCount string
+	HeadRoom         string
+}
+
+func newMemoryCalculatorEnv(buildpack cnb.Buildpack) memoryCalculatorEnv {
+	if strings.HasPrefix(buildpack.ID, "org.cloudfoundry.") {
+		return memoryCalculatorEnv{
+			ThreadCount:      "BPL_THREAD_COUNT",
+			LoadedClassCount: "BPL_LOADED_CLASS_COUNT",
+			HeadRoom:         "BPL_HEAD_ROOM",
+		}
+	}
+	return memoryCalculatorEnv{
+		ThreadCount:      "BPL_JVM_THREAD_COUNT",
+		LoadedClassCount: "BPL_JVM_LOADED_CLASS_COUNT",
+		HeadRoom:         "BPL_JVM_HEAD_ROOM",
+	}
+}
+
+// jvmMemory is the non-heap memory the calculator reserves for the JVM, in
+// bytes
+type jvmMemory struct {
+	Metaspace    int64
+	CodeCache    int64
+	DirectMemory int64
+	ThreadStacks int64
+	// HeadRoom is the percentage of the limit left for non-JVM processes
+	HeadRoom int
+}
+
+func newJVMMemory(threadCount, loadedClassCount, headRoom int) jvmMemory {
+	return jvmMemory{
+		Metaspace:    int64(loadedClassCount)*metaspacePerClass + metaspaceOverhead,
+		CodeCache:    codeCacheSize,
+		DirectMemory: directMemorySize,
+		ThreadStacks: int64(threadCount) * threadStackSize,
+		HeadRoom:     headRoom,
+	}
+}
+
+// MinimumLimit is the smallest memory limit that fits the JVM's non-heap
+// memory along with a minimal heap
+func (m jvmMemory) MinimumLimit() int64 {
+	jvm := m.Metaspace + m.CodeCache + m.DirectMemory + m.ThreadStacks + minimumHeapSize
+	return int64(math.Ceil(float64(jvm) * 100 / float64(100-m.HeadRoom)))
+}
+
+func (m jvmMemory) String() string {
+	return fmt.Sprintf("metaspace %dMi, code cache %dMi, direct memory %dMi, thread stacks %dMi, minimum heap %dMi, head room %d%%",
+		roundUp(m.Metaspace, mebibyte)/mebibyte,
+		m.CodeCache/mebibyte,
+		m.DirectMemory/mebibyte,
+		m.ThreadStacks/mebibyte,
+		minimumHeapSize/mebibyte,
+		m.HeadRoom,
+	)
+}
+
+func roundUp(value, increment int64) int64 {
+	return (value + increment - 1) / increment * increment
+}
+
+func findEnv(c *corev1.Container, name string) *corev1.EnvVar {
+	for i := range c.Env {
+		if c.Env[i].Name == name {
+			return &c.Env[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func jvmImageMetadata(buildpack, javaVersion string, classCount int) cnb.ImageMetadata {
+	return cnb.ImageMetadata{
+		Dependencies: []cnb.Dependency{
+			{Name: "openjdk-jre", Version: javaVersion, Kind: cnb.RuntimeDependency, Buildpack: cnb.Buildpack{ID: buildpack}},
+			{Name: "memory-calculator", Version: "4.1.0", Kind: cnb.RuntimeDependency, Buildpack: cnb.Buildpack{ID: buildpack}},
+		},
+		ClassCount: classCount,
+	}
+}
+
+func memory(value string) corev1.ResourceList {
+	return corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(value)}
+}
+
+func TestJVMMemoryCalculator(t *testing.T) {
+	paketo := jvmImageMetadata("paketo-buildpacks/bellsoft-liberica", "11.0.10", 1000)
+
+	tests := []struct {
+		name              string
+		imageMetadata     cnb.ImageMetadata
+		properties        SpringApplicationProperties
+		container         corev1.Container
+		expectedEnv       []corev1.EnvVar
+		expectedResources corev1.ResourceRequirements
+		expectedReason    string
+	}{{
+		name:          "defaults",
+		imageMetadata: paketo,
+		expectedResources: corev1.ResourceRequirements{
+			Limits:   memory("1Gi"),
+			Requests: memory("1Gi"),
+		},
+	}, {
+		name:          "tomcat threads",
+		imageMetadata: paketo,
+		properties:    SpringApplicationProperties{"server.tomcat.threads.max": "600"},
+		expectedEnv: []corev1.EnvVar{
+			{Name: "BPL_JVM_THREAD_COUNT", Value: "650"},
+		},
+		expectedResources: corev1.ResourceRequirements{
+			// 650Mi of thread stacks exceed the default limit
+			Limits:   memory("1280Mi"),
+			Requests: memory("1280Mi"),
+		},
+	}, {
+		name:          "cloud foundry tomcat threads",
+		imageMetadata: jvmImageMetadata("org.cloudfoundry.openjdk", "1.8.0_242", 1000),
+		properties:    SpringApplicationProperties{"server.tomcat.max-threads": "100"},
+		expectedEnv: []corev1.EnvVar{
+			{Name: "BPL_THREAD_COUNT", Value: "150"},
+		},
+		expectedResources: corev1.ResourceRequirements{
+			Limits:   memory("1Gi"),
+			Requests: memory("1Gi"),
+		},
+	}, {
+		name:          "thread count env",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Env: []corev1.EnvVar{{Name: "BPL_JVM_THREAD_COUNT", Value: "650"}},
+		},
+		expectedEnv: []corev1.EnvVar{
+			{Name: "BPL_JVM_THREAD_COUNT", Value: "650"},
+		},
+		expectedResources: corev1.ResourceRequirements{
+			Limits:   memory("1280Mi"),
+			Requests: memory("1280Mi"),
+		},
+	}, {
+		name:          "lower request",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Resources: corev1.ResourceRequirements{Requests: memory("512Mi")},
+		},
+		expectedResources: corev1.ResourceRequirements{
+			Limits:   memory("1Gi"),
+			Requests: memory("512Mi"),
+		},
+	}, {
+		name:          "higher request",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Resources: corev1.ResourceRequirements{Requests: memory("2Gi")},
+		},
+		expectedResources: corev1.ResourceRequirements{
+			Limits:   memory("2Gi"),
+			Requests: memory("2Gi"),
+		},
+	}, {
+		name:          "sufficient limit",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Resources: corev1.ResourceRequirements{Limits: memory("768Mi")},
+		},
+		expectedResources: corev1.ResourceRequirements{
+			Limits:   memory("768Mi"),
+			Requests: memory("768Mi"),
+		},
+	}, {
+		name:          "insufficient limit",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Resources: corev1.ResourceRequirements{Limits: memory("512Mi")},
+		},
+		expectedReason: "InsufficientMemory",
+	}, {
+		name:          "insufficient limit with head room",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Env:       []corev1.EnvVar{{Name: "BPL_JVM_HEAD_ROOM", Value: "20"}},
+			Resources: corev1.ResourceRequirements{Limits: memory("768Mi")},
+		},
+		expectedReason: "InsufficientMemory",
+	}, {
+		name:          "invalid thread count",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Env: []corev1.EnvVar{{Name: "BPL_JVM_THREAD_COUNT", Value: "lots"}},
+		},
+		expectedReason: "InvalidMemoryCalculatorInput",
+	}, {
+		name:          "invalid loaded class count",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Env: []corev1.EnvVar{{Name: "BPL_JVM_LOADED_CLASS_COUNT", Value: "1e4"}},
+		},
+		expectedReason: "InvalidMemoryCalculatorInput",
+	}, {
+		name:          "invalid head room",
+		imageMetadata: paketo,
+		container: corev1.Container{
+			Env: []corev1.EnvVar{{Name: "BPL_JVM_HEAD_ROOM", Value: "100"}},
+		},
+		expectedReason: "InvalidMemoryCalculatorInput",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := findOpinion(t, JVM, "jvm-memory-calculator")
+			ctx := StashSpringApplicationProperties(context.Background(), test.properties)
+			if !o.Applicable(ctx, AppliedOpinions{}, test.imageMetadata) {
+				t.Fatalf("expected opinion to be applicable")
+			}
+			test.container.Name = "app"
+			target := newTestResource(test.container)
+			err := o.Apply(ctx, target, 0, test.imageMetadata)
+			if test.expectedReason != "" {
+				var opinionErr *Error
+				if !errors.As(err, &opinionErr) || opinionErr.Reason != test.expectedReason {
+					t.Fatalf("expected error with reason %q, got %v", test.expectedReason, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			c := target.Template.Spec.Containers[0]
+			if diff := cmp.Diff(test.expectedEnv, c.Env); diff != "" {
+				t.Errorf("env (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedResources, c.Resources); diff != "" {
+				t.Errorf("resources (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestJVMMemoryCalculator_NotApplicable(t *testing.T) {
+	o := findOpinion(t, JVM, "jvm-memory-calculator")
+	imageMetadata := cnb.ImageMetadata{
+		Dependencies: []cnb.Dependency{
+			{Name: "openjdk-jre", Version: "11.0.10", Kind: cnb.RuntimeDependency},
+		},
+	}
+	if o.Applicable(context.Background(), AppliedOpinions{}, imageMetadata) {
+		t.Errorf("expected opinion not to be applicable without a memory calculator")
+	}
+}
+
+func TestJVMMemory_MinimumLimit(t *testing.T) {
+	tests := []struct {
+		name             string
+		threadCount      int
+		loadedClassCount int
+		headRoom         int
+		expected         int64
+	}{{
+		name:             "defaults",
+		threadCount:      250,
+		loadedClassCount: 8750,
+		// 250Mi of thread stacks, 240Mi of code cache, 10Mi of direct memory,
+		// 128Mi of heap and 8750 classes of metaspace
+		expected: 723255728,
+	}, {
+		name:             "head room",
+		threadCount:      250,
+		loadedClassCount: 8750,
+		headRoom:         50,
+		expected:         2 * 723255728,
+	}, {
+		name:             "no classes",
+		threadCount:      0,
+		loadedClassCount: 0,
+		expected:         metaspaceOverhead + codeCacheSize + directMemorySize + minimumHeapSize,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			memory := newJVMMemory(test.threadCount, test.loadedClassCount, test.headRoom)
+			if actual := memory.MinimumLimit(); actual != test.expected {
+				t.Errorf("expected minimum limit %d, got %d", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJVMClassCount(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected int
+	}{
+		{version: "1.8.0_242", expected: 19000},
+		{version: "8.0.282", expected: 19000},
+		{version: "9.0.4", expected: 23000},
+		{version: "11.0.10", expected: 24000},
+		{version: "16.0.1", expected: 24000},
+		{version: "17.0.1", expected: 26000},
+		{version: "not a version", expected: 26000},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			imageMetadata := jvmImageMetadata("paketo-buildpacks/bellsoft-liberica", test.version, 0)
+			if actual := jvmClassCount(imageMetadata); actual != test.expected {
+				t.Errorf("expected %d classes, got %d", test.expected, actual)
+			}
+		})
+	}
+	if actual := jvmClassCount(cnb.ImageMetadata{}); actual != 26000 {
+		t.Errorf("expected 26000 classes without a runtime, got %d", actual)
+	}
+}
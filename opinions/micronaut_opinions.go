@@ -0,0 +1,120 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var Micronaut = Opinions{
+	&BasicOpinion{
+		Id:           "micronaut",
+		Dependencies: []string{"micronaut-runtime"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			return bootMetadata.HasDependency("micronaut-runtime")
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			SetLabel(target, "apps.mononoke.local/framework", "micronaut")
+			SetAnnotation(target, "micronaut.io/version", bootMetadata.Dependency("micronaut-runtime").Version)
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id:           "micronaut-http-port",
+		DependsOn:    []string{"micronaut"},
+		Dependencies: []string{"micronaut-http-server-netty"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			return bootMetadata.HasDependency("micronaut-http-server-netty")
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			applicationProperties := GetSpringApplicationProperties(ctx)
+
+			serverPort := applicationProperties.Default("micronaut.server.port", "8080")
+			port, err := strconv.Atoi(serverPort)
+			if err != nil {
+				return err
+			}
+			if name := addContainerPort(target, containerIdx, int32(port)); name != "" {
+				// port is in use by a different container
+				return fmt.Errorf("desired port %s is in use by container %q, set 'micronaut.server.port' property to an open port", serverPort, name)
+			}
+
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id:        "micronaut-graceful-shutdown",
+		DependsOn: []string{"micronaut-http-port"},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			// micronaut closes the server as soon as it receives SIGTERM, keep
+			// serving until the pod is removed from the endpoints
+			addPreStopSleep(target, containerIdx)
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id: "micronaut-health-probes",
+		// probes use the port defaulted by the micronaut-http-port opinion
+		DependsOn:    []string{"micronaut-http-port"},
+		Dependencies: []string{"micronaut-management"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			return bootMetadata.HasDependency("micronaut-management")
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			applicationProperties := GetSpringApplicationProperties(ctx)
+
+			if applicationProperties["endpoints.health.enabled"] == "false" {
+				// the health endpoint was disabled by the user, skip
+				return nil
+			}
+			endpointsPort := applicationProperties["endpoints.all.port"]
+			if endpointsPort == "" {
+				endpointsPort = applicationProperties["micronaut.server.port"]
+			}
+			port, err := strconv.Atoi(endpointsPort)
+			if err != nil {
+				return err
+			}
+			endpointsPath := strings.TrimSuffix(applicationProperties["endpoints.all.path"], "/")
+
+			var livenessEndpoint, readinessEndpoint string
+			if bootMetadata.HasDependencyConstraint("micronaut-management", ">= 2.0.0-0") {
+				livenessEndpoint = "/health/liveness"
+				readinessEndpoint = "/health/readiness"
+			} else {
+				livenessEndpoint = "/health"
+				readinessEndpoint = "/health"
+			}
+
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+			defaultHTTPProbes(c, port, corev1.URISchemeHTTP, endpointsPath+livenessEndpoint, endpointsPath+readinessEndpoint)
+
+			return nil
+		},
+	},
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestMicronaut(t *testing.T) {
+	preStopSleep := &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{Command: []string{"sleep", "5"}},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		imageMetadata       cnb.ImageMetadata
+		properties          SpringApplicationProperties
+		expectedApplied     AppliedOpinions
+		expectedProperties  SpringApplicationProperties
+		expectedContainer   corev1.Container
+		expectedAnnotations map[string]string
+	}{{
+		name:                "not micronaut",
+		imageMetadata:       libraryMetadata("quarkus-core@1.13.0.Final"),
+		expectedApplied:     AppliedOpinions{},
+		expectedProperties:  SpringApplicationProperties{},
+		expectedContainer:   corev1.Container{Name: "app"},
+		expectedAnnotations: nil,
+	}, {
+		name:                "without http",
+		imageMetadata:       libraryMetadata("micronaut-runtime@2.4.2"),
+		expectedApplied:     AppliedOpinions{"micronaut"},
+		expectedProperties:  SpringApplicationProperties{},
+		expectedContainer:   corev1.Container{Name: "app"},
+		expectedAnnotations: map[string]string{"micronaut.io/version": "2.4.2"},
+	}, {
+		name:               "http",
+		imageMetadata:      libraryMetadata("micronaut-runtime@2.4.2", "micronaut-http-server-netty@2.4.2"),
+		expectedApplied:    AppliedOpinions{"micronaut", "micronaut-http-port", "micronaut-graceful-shutdown"},
+		expectedProperties: SpringApplicationProperties{"micronaut.server.port": "8080"},
+		expectedContainer: corev1.Container{
+			Name:      "app",
+			Ports:     []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			Lifecycle: preStopSleep,
+		},
+		expectedAnnotations: map[string]string{"micronaut.io/version": "2.4.2"},
+	}, {
+		name:               "health",
+		imageMetadata:      libraryMetadata("micronaut-runtime@2.4.2", "micronaut-http-server-netty@2.4.2", "micronaut-management@2.4.2"),
+		expectedApplied:    AppliedOpinions{"micronaut", "micronaut-http-port", "micronaut-graceful-shutdown", "micronaut-health-probes"},
+		expectedProperties: SpringApplicationProperties{"micronaut.server.port": "8080"},
+		expectedContainer: corev1.Container{
+			Name:           "app",
+			Ports:          []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			Lifecycle:      preStopSleep,
+			LivenessProbe:  httpGetProbe("/health/liveness", 8080, 30),
+			ReadinessProbe: httpGetProbe("/health/readiness", 8080, 0),
+		},
+		expectedAnnotations: map[string]string{"micronaut.io/version": "2.4.2"},
+	}, {
+		name:          "health on the endpoints port and path",
+		imageMetadata: libraryMetadata("micronaut-runtime@2.4.2", "micronaut-http-server-netty@2.4.2", "micronaut-management@2.4.2"),
+		properties: SpringApplicationProperties{
+			"endpoints.all.port": "9090",
+			"endpoints.all.path": "/manage/",
+		},
+		expectedApplied: AppliedOpinions{"micronaut", "micronaut-http-port", "micronaut-graceful-shutdown", "micronaut-health-probes"},
+		expectedProperties: SpringApplicationProperties{
+			"micronaut.server.port": "8080",
+			"endpoints.all.port":    "9090",
+			"endpoints.all.path":    "/manage/",
+		},
+		expectedContainer: corev1.Container{
+			Name:           "app",
+			Ports:          []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			Lifecycle:      preStopSleep,
+			LivenessProbe:  httpGetProbe("/manage/health/liveness", 9090, 30),
+			ReadinessProbe: httpGetProbe("/manage/health/readiness", 9090, 0),
+		},
+		expectedAnnotations: map[string]string{"micronaut.io/version": "2.4.2"},
+	}, {
+		name:               "health before 2.0",
+		imageMetadata:      libraryMetadata("micronaut-runtime@1.3.7", "micronaut-http-server-netty@1.3.7", "micronaut-management@1.3.7"),
+		expectedApplied:    AppliedOpinions{"micronaut", "micronaut-http-port", "micronaut-graceful-shutdown", "micronaut-health-probes"},
+		expectedProperties: SpringApplicationProperties{"micronaut.server.port": "8080"},
+		expectedContainer: corev1.Container{
+			Name:           "app",
+			Ports:          []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			Lifecycle:      preStopSleep,
+			LivenessProbe:  httpGetProbe("/health", 8080, 30),
+			ReadinessProbe: httpGetProbe("/health", 8080, 0),
+		},
+		expectedAnnotations: map[string]string{"micronaut.io/version": "1.3.7"},
+	}, {
+		name:            "health disabled",
+		imageMetadata:   libraryMetadata("micronaut-runtime@2.4.2", "micronaut-http-server-netty@2.4.2", "micronaut-management@2.4.2"),
+		properties:      SpringApplicationProperties{"endpoints.health.enabled": "false"},
+		expectedApplied: AppliedOpinions{"micronaut", "micronaut-http-port", "micronaut-graceful-shutdown", "micronaut-health-probes"},
+		expectedProperties: SpringApplicationProperties{
+			"micronaut.server.port":    "8080",
+			"endpoints.health.enabled": "false",
+		},
+		expectedContainer: corev1.Container{
+			Name:      "app",
+			Ports:     []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			Lifecycle: preStopSleep,
+		},
+		expectedAnnotations: map[string]string{"micronaut.io/version": "2.4.2"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			properties := SpringApplicationProperties{}
+			for key, value := range test.properties {
+				properties[key] = value
+			}
+			ctx := StashSpringApplicationProperties(context.Background(), properties)
+			target := newTestResource()
+
+			result, err := Micronaut.Apply(ctx, target, 0, test.imageMetadata, Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(test.expectedApplied, result.Applied); diff != "" {
+				t.Errorf("applied (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedProperties, properties); diff != "" {
+				t.Errorf("properties (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedContainer, target.Template.Spec.Containers[0]); diff != "" {
+				t.Errorf("container (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedAnnotations, target.Template.Annotations); diff != "" {
+				t.Errorf("annotations (-expected, +actual) = %v", diff)
+			}
+			if test.expectedContainer.Lifecycle != nil && *target.Template.Spec.TerminationGracePeriodSeconds != preStopGracePeriodSeconds {
+				t.Errorf("expected a %d second grace period, got %d", preStopGracePeriodSeconds, *target.Template.Spec.TerminationGracePeriodSeconds)
+			}
+			if len(test.expectedApplied) != 0 && target.Template.Labels["apps.mononoke.local/framework"] != "micronaut" {
+				t.Errorf("expected framework label, got %v", target.Template.Labels)
+			}
+		})
+	}
+}
+
+func TestMicronaut_GracefulShutdown(t *testing.T) {
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+	imageMetadata := libraryMetadata("micronaut-runtime@2.4.2", "micronaut-http-server-netty@2.4.2")
+	userPreStop := &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/drain", Port: intstr.FromInt(8080)},
+		},
+	}
+	target := newTestResource(corev1.Container{Name: "app", Lifecycle: userPreStop})
+	sixtySeconds := int64(60)
+	target.Template.Spec.TerminationGracePeriodSeconds = &sixtySeconds
+
+	if _, err := Micronaut.Apply(ctx, target, 0, imageMetadata, Options{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(userPreStop, target.Template.Spec.Containers[0].Lifecycle); diff != "" {
+		t.Errorf("lifecycle (-expected, +actual) = %v", diff)
+	}
+	if diff := cmp.Diff(&sixtySeconds, target.Template.Spec.TerminationGracePeriodSeconds); diff != "" {
+		t.Errorf("grace period (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestMicronaut_InvalidPort(t *testing.T) {
+	target := newTestResource()
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{"micronaut.server.port": "http"})
+	imageMetadata := libraryMetadata("micronaut-runtime@2.4.2", "micronaut-http-server-netty@2.4.2")
+	if _, err := Micronaut.Apply(ctx, target, 0, imageMetadata, Options{}); err == nil {
+		t.Errorf("expected error for an invalid port")
+	}
+}
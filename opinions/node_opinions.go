@@ -0,0 +1,247 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// portion of the memory limit given to the V8 heap, the rest is left for
+// buffers, native modules and the runtime itself
+const nodeHeapRatio = 0.75
+
+var NodeJS = Opinions{
+	&BasicOpinion{
+		Id:           "nodejs",
+		Dependencies: []string{"node"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			return imageMetadata.FindDependency("node", cnb.RuntimeDependency) != nil
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			framework := "nodejs"
+			if findNodePackage(imageMetadata, "express") != nil {
+				framework = "expressjs"
+			}
+			SetLabel(target, "apps.mononoke.local/framework", framework)
+			SetAnnotation(target, "apps.mononoke.local/node-version", imageMetadata.FindDependency("node", cnb.RuntimeDependency).Version)
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id:        "nodejs-port",
+		DependsOn: []string{"nodejs"},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+			env := findEnv(c, "PORT")
+			if env == nil {
+				c.Env = append(c.Env, corev1.EnvVar{Name: "PORT", Value: "8080"})
+				env = &c.Env[len(c.Env)-1]
+			}
+			if env.ValueFrom != nil {
+				// the port is only known once the pod starts
+				return nil
+			}
+			port, err := strconv.Atoi(env.Value)
+			if err != nil {
+				return &Error{
+					Reason:  "InvalidPort",
+					Message: fmt.Sprintf("environment variable PORT must be an integer, found %q", env.Value),
+				}
+			}
+
+			if name := addContainerPort(target, containerIdx, int32(port)); name != "" {
+				// port is in use by a different container
+				return fmt.Errorf("desired port %d is in use by container %q, set the PORT environment variable to an open port", port, name)
+			}
+
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id:        "nodejs-env",
+		DependsOn: []string{"nodejs"},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+			if findEnv(c, "NODE_ENV") == nil {
+				c.Env = append(c.Env, corev1.EnvVar{Name: "NODE_ENV", Value: "production"})
+			}
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id:        "nodejs-heap",
+		DependsOn: []string{"nodejs"},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+			limit, ok := c.Resources.Limits[corev1.ResourceMemory]
+			if !ok || hasNodeOption(c, "--max-old-space-size") {
+				return nil
+			}
+			heap := int64(nodeHeapRatio*float64(limit.Value())) / mebibyte
+			if heap < 1 {
+				heap = 1
+			}
+			addNodeOptions(c, fmt.Sprintf("--max-old-space-size=%d", heap))
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id:        "nodejs-graceful-shutdown",
+		DependsOn: []string{"nodejs"},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			// node exits on SIGTERM unless the application handles it, keep
+			// serving until the pod is removed from the endpoints
+			addPreStopSleep(target, containerIdx)
+			return nil
+		},
+	},
+
+	// service intents
+	&NodeServiceIntent{
+		Id:        "nodejs-service-intent-mysql",
+		LabelName: "services.mononoke.local/mysql",
+		Packages: sets.NewString(
+			"mysql",
+			"mysql2",
+		),
+	},
+	&NodeServiceIntent{
+		Id:        "nodejs-service-intent-postgres",
+		LabelName: "services.mononoke.local/postgres",
+		Packages: sets.NewString(
+			"pg",
+		),
+	},
+	&NodeServiceIntent{
+		Id:        "nodejs-service-intent-mongodb",
+		LabelName: "services.mononoke.local/mongodb",
+		Packages: sets.NewString(
+			"mongodb",
+		),
+	},
+	&NodeServiceIntent{
+		Id:        "nodejs-service-intent-redis",
+		LabelName: "services.mononoke.local/redis",
+		Packages: sets.NewString(
+			"ioredis",
+			"redis",
+		),
+	},
+	&NodeServiceIntent{
+		Id:        "nodejs-service-intent-kafka",
+		LabelName: "services.mononoke.local/kafka",
+		Packages: sets.NewString(
+			"kafkajs",
+		),
+	},
+}
+
+// isNodePackage returns true for dependencies installed from npm, identified
+// by their package URL or the buildpack that installed them
+func isNodePackage(d cnb.Dependency) bool {
+	if d.Kind != cnb.LibraryDependency {
+		return false
+	}
+	if d.PURL != "" {
+		return strings.HasPrefix(d.PURL, "pkg:npm/")
+	}
+	return strings.Contains(d.Buildpack.ID, "npm") || strings.Contains(d.Buildpack.ID, "yarn")
+}
+
+func findNodePackage(imageMetadata cnb.ImageMetadata, name string) *cnb.Dependency {
+	for i := range imageMetadata.Dependencies {
+		if d := &imageMetadata.Dependencies[i]; isNodePackage(*d) && d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// addNodeOptions appends the options to the NODE_OPTIONS env var of the
+// container
+func addNodeOptions(c *corev1.Container, opts ...string) {
+	nodeOptions := findEnv(c, "NODE_OPTIONS")
+	if nodeOptions == nil {
+		c.Env = append(c.Env, corev1.EnvVar{Name: "NODE_OPTIONS"})
+		nodeOptions = &c.Env[len(c.Env)-1]
+	}
+	nodeOptions.Value = strings.TrimSpace(nodeOptions.Value + " " + strings.Join(opts, " "))
+}
+
+// hasNodeOption returns true if NODE_OPTIONS has the option
+func hasNodeOption(c *corev1.Container, name string) bool {
+	nodeOptions := findEnv(c, "NODE_OPTIONS")
+	if nodeOptions == nil {
+		return false
+	}
+	for _, opt := range strings.Fields(nodeOptions.Value) {
+		if opt == name || strings.HasPrefix(opt, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeServiceIntent labels applications that install a client package of a
+// service from npm
+type NodeServiceIntent struct {
+	Id        string
+	LabelName string
+	Packages  sets.String
+}
+
+func (o *NodeServiceIntent) GetId() string {
+	return o.Id
+}
+
+func (o *NodeServiceIntent) GetDependsOn() []string {
+	return []string{"nodejs"}
+}
+
+func (o *NodeServiceIntent) GetAfter() []string {
+	return nil
+}
+
+func (o *NodeServiceIntent) Applicable(ctx context.Context, applied AppliedOpinions, metadata cnb.ImageMetadata) bool {
+	return len(o.MatchedDependencies(metadata)) != 0
+}
+
+func (o *NodeServiceIntent) MatchedDependencies(metadata cnb.ImageMetadata) []cnb.Dependency {
+	matched := []cnb.Dependency{}
+	for _, d := range metadata.Dependencies {
+		if isNodePackage(d) && o.Packages.Has(d.Name) {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+func (o *NodeServiceIntent) Apply(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error {
+	for _, d := range o.MatchedDependencies(metadata) {
+		SetLabel(target, o.LabelName, target.PodTemplate().Spec.Containers[containerIdx].Name)
+		SetAnnotation(target, o.LabelName, fmt.Sprintf("%s/%s", d.Name, d.Version))
+		break
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// nodeImageMetadata describes an image with the node runtime and the npm
+// packages, given as `name@version`
+func nodeImageMetadata(packages ...string) cnb.ImageMetadata {
+	imageMetadata := libraryMetadata(packages...)
+	for i := range imageMetadata.Dependencies {
+		d := &imageMetadata.Dependencies[i]
+		d.PURL = "pkg:npm/" + d.Name + "@" + d.Version
+	}
+	imageMetadata.Dependencies = append(imageMetadata.Dependencies, cnb.Dependency{
+		Name:      "node",
+		Version:   "14.16.1",
+		Kind:      cnb.RuntimeDependency,
+		Buildpack: cnb.Buildpack{ID: "paketo-buildpacks/node-engine"},
+	})
+	return imageMetadata
+}
+
+func TestNodeJS(t *testing.T) {
+	tenSeconds := int64(10)
+	sixtySeconds := int64(60)
+	preStopGracePeriod := int64(preStopGracePeriodSeconds)
+	preStopSleep := &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{Command: []string{"sleep", "5"}},
+		},
+	}
+	userPreStop := &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{Command: []string{"/workspace/drain.sh"}},
+		},
+	}
+	portFromConfigMap := &corev1.EnvVarSource{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+			Key:                  "port",
+		},
+	}
+
+	tests := []struct {
+		name                string
+		imageMetadata       cnb.ImageMetadata
+		container           corev1.Container
+		gracePeriodSeconds  *int64
+		expectedApplied     AppliedOpinions
+		expectedContainer   corev1.Container
+		expectedGracePeriod *int64
+		expectedLabels      map[string]string
+	}{{
+		name:                "not node",
+		imageMetadata:       libraryMetadata("spring-boot@2.3.0.RELEASE"),
+		container:           corev1.Container{Name: "app"},
+		expectedApplied:     AppliedOpinions{},
+		expectedContainer:   corev1.Container{Name: "app"},
+		expectedGracePeriod: nil,
+		expectedLabels:      nil,
+	}, {
+		name:            "defaults",
+		imageMetadata:   nodeImageMetadata(),
+		container:       corev1.Container{Name: "app"},
+		expectedApplied: AppliedOpinions{"nodejs", "nodejs-port", "nodejs-env", "nodejs-heap", "nodejs-graceful-shutdown"},
+		expectedContainer: corev1.Container{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PORT", Value: "8080"},
+				{Name: "NODE_ENV", Value: "production"},
+			},
+			Ports:     []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			Lifecycle: preStopSleep,
+		},
+		expectedGracePeriod: &preStopGracePeriod,
+		expectedLabels:      map[string]string{"apps.mononoke.local/framework": "nodejs"},
+	}, {
+		name:          "express with a memory limit",
+		imageMetadata: nodeImageMetadata("express@4.17.1"),
+		container: corev1.Container{
+			Name:      "app",
+			Resources: corev1.ResourceRequirements{Limits: memory("512Mi")},
+		},
+		gracePeriodSeconds: &tenSeconds,
+		expectedApplied:    AppliedOpinions{"nodejs", "nodejs-port", "nodejs-env", "nodejs-heap", "nodejs-graceful-shutdown"},
+		expectedContainer: corev1.Container{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PORT", Value: "8080"},
+				{Name: "NODE_ENV", Value: "production"},
+				{Name: "NODE_OPTIONS", Value: "--max-old-space-size=384"},
+			},
+			Ports:     []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			Resources: corev1.ResourceRequirements{Limits: memory("512Mi")},
+		},
+		// too short to spare the preStop sleep
+		expectedGracePeriod: &tenSeconds,
+		expectedLabels:      map[string]string{"apps.mononoke.local/framework": "expressjs"},
+	}, {
+		name:          "user configuration",
+		imageMetadata: nodeImageMetadata(),
+		container: corev1.Container{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PORT", Value: "3000"},
+				{Name: "NODE_ENV", Value: "development"},
+				{Name: "NODE_OPTIONS", Value: "--max-old-space-size=100 --trace-warnings"},
+			},
+			Resources: corev1.ResourceRequirements{Limits: memory("512Mi")},
+		},
+		expectedApplied: AppliedOpinions{"nodejs", "nodejs-port", "nodejs-env", "nodejs-heap", "nodejs-graceful-shutdown"},
+		expectedContainer: corev1.Container{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PORT", Value: "3000"},
+				{Name: "NODE_ENV", Value: "development"},
+				{Name: "NODE_OPTIONS", Value: "--max-old-space-size=100 --trace-warnings"},
+			},
+			Ports:     []corev1.ContainerPort{{ContainerPort: 3000, Protocol: corev1.ProtocolTCP}},
+			Resources: corev1.ResourceRequirements{Limits: memory("512Mi")},
+			Lifecycle: preStopSleep,
+		},
+		expectedGracePeriod: &preStopGracePeriod,
+		expectedLabels:      map[string]string{"apps.mononoke.local/framework": "nodejs"},
+	}, {
+		name:          "user preStop hook",
+		imageMetadata: nodeImageMetadata(),
+		container: corev1.Container{
+			Name:      "app",
+			Lifecycle: userPreStop,
+		},
+		gracePeriodSeconds: &sixtySeconds,
+		expectedApplied:    AppliedOpinions{"nodejs", "nodejs-port", "nodejs-env", "nodejs-heap", "nodejs-graceful-shutdown"},
+		expectedContainer: corev1.Container{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PORT", Value: "8080"},
+				{Name: "NODE_ENV", Value: "production"},
+			},
+			Ports:     []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			Lifecycle: userPreStop,
+		},
+		expectedGracePeriod: &sixtySeconds,
+		expectedLabels:      map[string]string{"apps.mononoke.local/framework": "nodejs"},
+	}, {
+		name:          "port from a config map",
+		imageMetadata: nodeImageMetadata(),
+		container: corev1.Container{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PORT", ValueFrom: portFromConfigMap},
+			},
+		},
+		expectedApplied: AppliedOpinions{"nodejs", "nodejs-port", "nodejs-env", "nodejs-heap", "nodejs-graceful-shutdown"},
+		expectedContainer: corev1.Container{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PORT", ValueFrom: portFromConfigMap},
+				{Name: "NODE_ENV", Value: "production"},
+			},
+			Lifecycle: preStopSleep,
+		},
+		expectedGracePeriod: &preStopGracePeriod,
+		expectedLabels:      map[string]string{"apps.mononoke.local/framework": "nodejs"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+			target := newTestResource(test.container)
+			target.Template.Spec.TerminationGracePeriodSeconds = test.gracePeriodSeconds
+
+			result, err := NodeJS.Apply(ctx, target, 0, test.imageMetadata, Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(test.expectedApplied, result.Applied); diff != "" {
+				t.Errorf("applied (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedContainer, target.Template.Spec.Containers[0]); diff != "" {
+				t.Errorf("container (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedGracePeriod, target.Template.Spec.TerminationGracePeriodSeconds); diff != "" {
+				t.Errorf("grace period (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedLabels, target.Template.Labels); diff != "" {
+				t.Errorf("labels (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestNodeJS_InvalidPort(t *testing.T) {
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+	target := newTestResource(corev1.Container{
+		Name: "app",
+		Env:  []corev1.EnvVar{{Name: "PORT", Value: "http"}},
+	})
+
+	_, err := NodeJS.Apply(ctx, target, 0, nodeImageMetadata(), Options{})
+	if opinionErr, ok := err.(*Error); !ok || opinionErr.Reason != "InvalidPort" {
+		t.Errorf("expected InvalidPort error, got %v", err)
+	}
+}
+
+func TestNodeServiceIntent(t *testing.T) {
+	tests := []struct {
+		name                string
+		imageMetadata       cnb.ImageMetadata
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+	}{{
+		name:          "npm package",
+		imageMetadata: nodeImageMetadata("pg@8.6.0", "ioredis@4.27.1"),
+		expectedLabels: map[string]string{
+			"apps.mononoke.local/framework":    "nodejs",
+			"services.mononoke.local/postgres": "app",
+			"services.mononoke.local/redis":    "app",
+		},
+		expectedAnnotations: map[string]string{
+			"apps.mononoke.local/node-version": "14.16.1",
+			"services.mononoke.local/postgres": "pg/8.6.0",
+			"services.mononoke.local/redis":    "ioredis/4.27.1",
+		},
+	}, {
+		name: "package installed by the npm buildpack",
+		imageMetadata: func() cnb.ImageMetadata {
+			imageMetadata := nodeImageMetadata()
+			imageMetadata.Dependencies = append(imageMetadata.Dependencies, cnb.Dependency{
+				Name:      "mysql2",
+				Version:   "2.2.5",
+				Kind:      cnb.LibraryDependency,
+				Buildpack: cnb.Buildpack{ID: "paketo-buildpacks/npm-install"},
+			})
+			return imageMetadata
+		}(),
+		expectedLabels: map[string]string{
+			"apps.mononoke.local/framework": "nodejs",
+			"services.mononoke.local/mysql": "app",
+		},
+		expectedAnnotations: map[string]string{
+			"apps.mononoke.local/node-version": "14.16.1",
+			"services.mononoke.local/mysql":    "mysql2/2.2.5",
+		},
+	}, {
+		name: "jar with the name of an npm package",
+		imageMetadata: func() cnb.ImageMetadata {
+			imageMetadata := nodeImageMetadata()
+			imageMetadata.Dependencies = append(imageMetadata.Dependencies, cnb.Dependency{
+				Name:    "redis",
+				Version: "3.6.3",
+				PURL:    "pkg:maven/org.example/redis@3.6.3",
+				Kind:    cnb.LibraryDependency,
+			})
+			return imageMetadata
+		}(),
+		expectedLabels: map[string]string{
+			"apps.mononoke.local/framework": "nodejs",
+		},
+		expectedAnnotations: map[string]string{
+			"apps.mononoke.local/node-version": "14.16.1",
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+			target := newTestResource()
+
+			if _, err := NodeJS.Apply(ctx, target, 0, test.imageMetadata, Options{}); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(test.expectedLabels, target.Template.Labels); diff != "" {
+				t.Errorf("labels (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedAnnotations, target.Template.Annotations); diff != "" {
+				t.Errorf("annotations (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
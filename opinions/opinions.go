@@ -18,30 +18,342 @@ package opinions
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/spring-cloud-incubator/mononoke/cnb"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type Opinion interface {
 	GetId() string
-	Applicable(applied AppliedOpinions, imageMetadata cnb.BuildMetadata) bool
-	Apply(ctx context.Context, target Resource, containerIdx int, metadata cnb.BuildMetadata) error
+	Applicable(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool
+	Apply(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error
 }
 
+// DependentOpinion is an opinion that is applied after other opinions
+type DependentOpinion interface {
+	Opinion
+	// GetDependsOn lists opinions that must be applied before the opinion.
+	// The opinion is not applied unless every one of them was applied.
+	GetDependsOn() []string
+	// GetAfter lists opinions that are applied before the opinion, when
+	// they are applied at all
+	GetAfter() []string
+}
+
+var _ DependentOpinion = (*BasicOpinion)(nil)
+
 type Opinions []Opinion
 
-func (os Opinions) Apply(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.BuildMetadata) ([]string, error) {
-	applied := AppliedOpinions{}
+// Default opinions applied to applications, in order
+var Default = append(append(append(append(append(Opinions{}, JVM...), SpringBoot...), Quarkus...), Micronaut...), NodeJS...)
+
+// Error is returned by opinions that can't be applied to a resource, for a
+// reason the user can act on. The reason is reflected on the resource's
+// status.
+type Error struct {
+	Reason  string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// IgnoredError is returned by an opinion that failed without changing the
+// resource, when the failure is not to fail applying opinions. The opinion is
+// skipped with the reason instead.
+type IgnoredError struct {
+	Reason  string
+	Message string
+}
+
+func (e *IgnoredError) Error() string {
+	return e.Message
+}
+
+// Options for applying opinions. Every opinion is selected when Enabled is
+// empty. Disabled opinions are never selected. Explain records the decision
+// made for every opinion.
+type Options struct {
+	Enabled  []string
+	Disabled []string
+	Explain  bool
+}
+
+const (
+	// DisabledReason is the reason an opinion listed in Options.Disabled is
+	// skipped
+	DisabledReason = "Disabled"
+	// NotEnabledReason is the reason an opinion missing from
+	// Options.Enabled is skipped
+	NotEnabledReason = "NotEnabled"
+	// PrerequisiteSkippedReason is the reason an opinion is skipped when an
+	// opinion it depends on was skipped
+	PrerequisiteSkippedReason = "PrerequisiteSkipped"
+	// PrerequisiteNotAppliedReason is the reason an opinion is not applied
+	// when an opinion it depends on was not applicable
+	PrerequisiteNotAppliedReason = "PrerequisiteNotApplied"
+	// NotApplicableReason is the reason an opinion is not applied when it
+	// doesn't apply to the image
+	NotApplicableReason = "NotApplicable"
+	// UnknownOpinionReason is the reason an ID that matches no opinion is
+	// reported
+	UnknownOpinionReason = "UnknownOpinion"
+)
+
+// skipReason returns the reason the opinion is not selected, or an empty
+// string when the opinion is selected
+func (s Options) skipReason(id string) string {
+	for _, d := range s.Disabled {
+		if d == id {
+			return DisabledReason
+		}
+	}
+	if len(s.Enabled) == 0 {
+		return ""
+	}
+	for _, e := range s.Enabled {
+		if e == id {
+			return ""
+		}
+	}
+	return NotEnabledReason
+}
+
+// unknownIds returns the IDs that match no opinion
+func unknownIds(os Opinions, ids []string) []string {
+	known := map[string]bool{}
+	for _, o := range os {
+		known[o.GetId()] = true
+	}
+	unknown := []string{}
+	for _, id := range ids {
+		if !known[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	return unknown
+}
+
+// unknownEnabledIds returns an error listing the enabled IDs that match no
+// opinion, as a misspelled ID would otherwise silently skip every opinion.
+// Disabling an unknown opinion, like a custom opinion that was deleted, has
+// no effect and is reported as a skipped opinion instead.
+func (s Options) unknownEnabledIds(os Opinions) error {
+	unknown := unknownIds(os, s.Enabled)
+	if len(unknown) == 0 {
+		return nil
+	}
+	return &Error{
+		Reason:  UnknownOpinionReason,
+		Message: fmt.Sprintf("enabled opinions %s are unknown", strings.Join(unknown, ", ")),
+	}
+}
+
+// SkippedOpinion is an opinion that was not selected, that depends on an
+// opinion that was not selected, or whose failure was ignored
+type SkippedOpinion struct {
+	Id     string
+	Reason string
+	// Message describes the ignored failure
+	Message string
+}
+
+// Result of applying opinions
+type Result struct {
+	// Applied opinions, in the order they were applied
+	Applied AppliedOpinions
+	// Skipped opinions, as they or a prerequisite were not selected, or
+	// their failure was ignored
+	Skipped []SkippedOpinion
+	// Decisions made for every opinion, when explained
+	Decisions []Decision
+}
+
+// Apply applies the selected opinions that are applicable, sorted so every
+// opinion is applied after the opinions it depends on or is after.
+func (os Opinions) Apply(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata, options Options) (*Result, error) {
+	sorted, err := os.Sorted()
+	if err != nil {
+		return nil, err
+	}
+	if err := options.unknownEnabledIds(sorted); err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Applied: AppliedOpinions{},
+		Skipped: []SkippedOpinion{},
+	}
+	for _, id := range unknownIds(sorted, options.Disabled) {
+		result.Skipped = append(result.Skipped, SkippedOpinion{Id: id, Reason: UnknownOpinionReason, Message: "disabled opinion is unknown"})
+		if options.Explain {
+			result.Decisions = append(result.Decisions, Decision{Id: id, Reason: UnknownOpinionReason})
+		}
+	}
+	skippedIds := map[string]bool{}
+	decide := func(o Opinion, reason string) {
+		if options.Explain {
+			result.Decisions = append(result.Decisions, Decision{Id: o.GetId(), Reason: reason})
+		}
+	}
+	for _, o := range sorted {
+		if reason := options.skipReason(o.GetId()); reason != "" {
+			result.Skipped = append(result.Skipped, SkippedOpinion{Id: o.GetId(), Reason: reason})
+			skippedIds[o.GetId()] = true
+			decide(o, reason)
+			continue
+		}
+		if reason := prerequisiteSkipReason(o, result.Applied, skippedIds); reason != "" {
+			if reason == PrerequisiteSkippedReason {
+				result.Skipped = append(result.Skipped, SkippedOpinion{Id: o.GetId(), Reason: reason})
+				skippedIds[o.GetId()] = true
+			}
+			decide(o, reason)
+			continue
+		}
+		if !o.Applicable(ctx, result.Applied, imageMetadata) {
+			decide(o, NotApplicableReason)
+			continue
+		}
+		var decision *Decision
+		if options.Explain {
+			decision, err = explain(ctx, o, target, containerIdx, imageMetadata)
+		} else {
+			err = o.Apply(ctx, target, containerIdx, imageMetadata)
+		}
+		var ignored *IgnoredError
+		if errors.As(err, &ignored) {
+			result.Skipped = append(result.Skipped, SkippedOpinion{Id: o.GetId(), Reason: ignored.Reason, Message: ignored.Message})
+			skippedIds[o.GetId()] = true
+			decide(o, ignored.Reason)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Applied = append(result.Applied, o.GetId())
+		if decision != nil {
+			result.Decisions = append(result.Decisions, *decision)
+		}
+	}
+	return result, nil
+}
+
+// prerequisiteSkipReason returns PrerequisiteSkippedReason when an opinion
+// the opinion depends on was skipped, a different reason when a prerequisite
+// was not applied as it was not applicable, or an empty string when every
+// prerequisite was applied
+func prerequisiteSkipReason(o Opinion, applied AppliedOpinions, skipped map[string]bool) string {
+	d, ok := o.(DependentOpinion)
+	if !ok {
+		return ""
+	}
+	reason := ""
+	for _, id := range d.GetDependsOn() {
+		if skipped[id] {
+			return PrerequisiteSkippedReason
+		}
+		if !applied.Has(id) {
+			reason = PrerequisiteNotAppliedReason
+		}
+	}
+	return reason
+}
+
+// Sorted returns the opinions sorted so every opinion follows the opinions
+// it depends on or is after. Otherwise the order of the opinions is kept. An
+// error is returned when an opinion depends on or is after an unknown
+// opinion, or when the dependencies of opinions form a cycle.
+func (os Opinions) Sorted() (Opinions, error) {
+	index := map[string]int{}
+	for i, o := range os {
+		index[o.GetId()] = i
+	}
+	// dependents[i] are the opinions that must follow opinion i
+	dependents := make([][]int, len(os))
+	prerequisites := make([]int, len(os))
+	for i, o := range os {
+		d, ok := o.(DependentOpinion)
+		if !ok {
+			continue
+		}
+		for _, id := range append(append([]string{}, d.GetDependsOn()...), d.GetAfter()...) {
+			j, ok := index[id]
+			if !ok {
+				return nil, &Error{
+					Reason:  UnknownOpinionReason,
+					Message: fmt.Sprintf("opinion %s can't be ordered, it depends on or is after unknown opinion %s", o.GetId(), id),
+				}
+			}
+			dependents[j] = append(dependents[j], i)
+			prerequisites[i]++
+		}
+	}
+	sorted := make(Opinions, 0, len(os))
+	done := make([]bool, len(os))
+	for len(sorted) < len(os) {
+		// the first opinion, in the original order, whose prerequisites are sorted
+		next := -1
+		for i := range os {
+			if !done[i] && prerequisites[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			cycle := []string{}
+			for i, o := range os {
+				if !done[i] {
+					cycle = append(cycle, o.GetId())
+				}
+			}
+			return nil, &Error{
+				Reason:  "OpinionCycle",
+				Message: fmt.Sprintf("opinions %s can't be ordered, their dependencies form a cycle", strings.Join(cycle, ", ")),
+			}
+		}
+		done[next] = true
+		sorted = append(sorted, os[next])
+		for _, i := range dependents[next] {
+			prerequisites[i]--
+		}
+	}
+	return sorted, nil
+}
+
+// Cyclic returns the IDs of the opinions whose dependencies lead back to
+// themselves, in order. Dependencies on unknown opinions are ignored.
+func (os Opinions) Cyclic() []string {
+	prerequisites := map[string][]string{}
 	for _, o := range os {
-		if o.Applicable(applied, imageMetadata) {
-			applied = append(applied, o.GetId())
-			if err := o.Apply(ctx, target, containerIdx, imageMetadata); err != nil {
-				return nil, err
+		if d, ok := o.(DependentOpinion); ok {
+			prerequisites[o.GetId()] = append(append([]string{}, d.GetDependsOn()...), d.GetAfter()...)
+		}
+	}
+	cyclic := []string{}
+	for _, o := range os {
+		seen := map[string]bool{}
+		pending := append([]string{}, prerequisites[o.GetId()]...)
+		for len(pending) != 0 {
+			id := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			if seen[id] {
+				continue
 			}
+			seen[id] = true
+			pending = append(pending, prerequisites[id]...)
+		}
+		if seen[o.GetId()] {
+			cyclic = append(cyclic, o.GetId())
 		}
 	}
-	return applied, nil
+	return cyclic
 }
 
 type AppliedOpinions []string
@@ -56,26 +368,158 @@ func (os AppliedOpinions) Has(id string) bool {
 }
 
 type BasicOpinion struct {
-	Id             string
-	ApplicableFunc func(applied AppliedOpinions, metadata cnb.BuildMetadata) bool
-	ApplyFunc      func(ctx context.Context, target Resource, containerIdx int, metadata cnb.BuildMetadata) error
+	Id string
+	// DependsOn lists opinions that must be applied before the opinion
+	DependsOn []string
+	// After lists opinions that are applied before the opinion, when they
+	// are applied
+	After []string
+	// Dependencies of the image the opinion is applicable for, reported when
+	// the opinion is explained
+	Dependencies   []string
+	ApplicableFunc func(ctx context.Context, applied AppliedOpinions, metadata cnb.ImageMetadata) bool
+	ApplyFunc      func(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error
 }
 
 func (o *BasicOpinion) GetId() string {
 	return o.Id
 }
 
-func (o *BasicOpinion) Applicable(applied AppliedOpinions, metadata cnb.BuildMetadata) bool {
+func (o *BasicOpinion) GetDependsOn() []string {
+	return o.DependsOn
+}
+
+func (o *BasicOpinion) GetAfter() []string {
+	return o.After
+}
+
+func (o *BasicOpinion) MatchedDependencies(metadata cnb.ImageMetadata) []cnb.Dependency {
+	return matchDependencies(metadata, o.Dependencies)
+}
+
+// matchDependencies returns the dependencies of the image with one of the
+// names
+func matchDependencies(metadata cnb.ImageMetadata, names []string) []cnb.Dependency {
+	matched := []cnb.Dependency{}
+	for _, d := range metadata.Dependencies {
+		for _, name := range names {
+			if d.Name == name {
+				matched = append(matched, d)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func (o *BasicOpinion) Applicable(ctx context.Context, applied AppliedOpinions, metadata cnb.ImageMetadata) bool {
 	if o.ApplicableFunc == nil {
 		return true
 	}
-	return o.ApplicableFunc(applied, metadata)
+	return o.ApplicableFunc(ctx, applied, metadata)
 }
 
-func (o *BasicOpinion) Apply(ctx context.Context, target Resource, containerIdx int, metadata cnb.BuildMetadata) error {
+func (o *BasicOpinion) Apply(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error {
 	return o.ApplyFunc(ctx, target, containerIdx, metadata)
 }
 
+// addContainerPort adds the port to the target container, unless already
+// defined. The name of a different container that defines the port is
+// returned, as the port can't be added.
+func addContainerPort(target Resource, containerIdx int, port int32) string {
+	c := &target.PodTemplate().Spec.Containers[containerIdx]
+	if name, cp := findContainerPort(target.PodTemplate().Spec, port); cp == nil {
+		c.Ports = append(c.Ports, corev1.ContainerPort{
+			ContainerPort: port,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	} else if name != c.Name {
+		return name
+	}
+	return ""
+}
+
+// applicationGracePeriodSeconds makes the termination grace period of the pod
+// explicit, and returns the portion of it allocated to the application to
+// shut down gracefully
+func applicationGracePeriodSeconds(target Resource) int {
+	var k8sGracePeriodSeconds int64 = 30 // default k8s grace period is 30 seconds
+	if target.PodTemplate().Spec.TerminationGracePeriodSeconds != nil {
+		k8sGracePeriodSeconds = *target.PodTemplate().Spec.TerminationGracePeriodSeconds
+	}
+	target.PodTemplate().Spec.TerminationGracePeriodSeconds = &k8sGracePeriodSeconds
+	// allocate 80% of the k8s grace period to the application
+	return int(math.Floor(0.8 * float64(k8sGracePeriodSeconds)))
+}
+
+// preStopSleepSeconds is how long a terminating pod keeps serving before
+// SIGTERM, giving endpoints and load balancers time to stop routing to it
+const preStopSleepSeconds = 5
+
+// preStopGracePeriodSeconds replaces an unset termination grace period when a
+// preStop hook is added, as the hook counts against the grace period
+const preStopGracePeriodSeconds = 45
+
+// addPreStopSleep delays SIGTERM to the container with a preStop hook, and
+// lengthens an unset termination grace period to cover the delay. An existing
+// preStop hook is left as is, as is a grace period too short to spare the
+// delay.
+func addPreStopSleep(target Resource, containerIdx int) {
+	spec := &target.PodTemplate().Spec
+	if spec.TerminationGracePeriodSeconds == nil {
+		var gracePeriodSeconds int64 = preStopGracePeriodSeconds
+		spec.TerminationGracePeriodSeconds = &gracePeriodSeconds
+	}
+	c := &spec.Containers[containerIdx]
+	if c.Lifecycle != nil && c.Lifecycle.PreStop != nil {
+		return
+	}
+	if *spec.TerminationGracePeriodSeconds <= 2*preStopSleepSeconds {
+		return
+	}
+	if c.Lifecycle == nil {
+		c.Lifecycle = &corev1.Lifecycle{}
+	}
+	c.Lifecycle.PreStop = &corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"sleep", strconv.Itoa(preStopSleepSeconds)},
+		},
+	}
+}
+
+// defaultHTTPProbes sets HTTP GET handlers on the liveness and readiness
+// probes of the container, unless the probes define a handler
+func defaultHTTPProbes(c *corev1.Container, port int, scheme corev1.URIScheme, livenessPath, readinessPath string) {
+	if c.LivenessProbe == nil {
+		c.LivenessProbe = &corev1.Probe{
+			// increase default to give more time to start
+			// TODO(scothis) remove if a StartupProbe is defined
+			InitialDelaySeconds: 30,
+		}
+	}
+	if c.LivenessProbe.Handler == (corev1.Handler{}) {
+		c.LivenessProbe.Handler = corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   livenessPath,
+				Port:   intstr.FromInt(port),
+				Scheme: scheme,
+			},
+		}
+	}
+	if c.ReadinessProbe == nil {
+		c.ReadinessProbe = &corev1.Probe{}
+	}
+	if c.ReadinessProbe.Handler == (corev1.Handler{}) {
+		c.ReadinessProbe.Handler = corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   readinessPath,
+				Port:   intstr.FromInt(port),
+				Scheme: scheme,
+			},
+		}
+	}
+}
+
 func findContainerPort(ps corev1.PodSpec, port int32) (string, *corev1.ContainerPort) {
 	for _, c := range ps.Containers {
 		for _, p := range c.Ports {
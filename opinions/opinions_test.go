@@ -0,0 +1,275 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+)
+
+func TestOpinions_Sorted(t *testing.T) {
+	tests := []struct {
+		name           string
+		opinions       Opinions
+		expected       []string
+		expectedReason string
+	}{{
+		name: "keeps the declared order",
+		opinions: Opinions{
+			&BasicOpinion{Id: "c"},
+			&BasicOpinion{Id: "a"},
+			&BasicOpinion{Id: "b"},
+		},
+		expected: []string{"c", "a", "b"},
+	}, {
+		name: "depends on",
+		opinions: Opinions{
+			&BasicOpinion{Id: "a", DependsOn: []string{"c"}},
+			&BasicOpinion{Id: "b"},
+			&BasicOpinion{Id: "c"},
+		},
+		expected: []string{"b", "c", "a"},
+	}, {
+		name: "after",
+		opinions: Opinions{
+			&BasicOpinion{Id: "a", After: []string{"b"}},
+			&BasicOpinion{Id: "b"},
+			&BasicOpinion{Id: "c"},
+		},
+		expected: []string{"b", "a", "c"},
+	}, {
+		name: "transitive",
+		opinions: Opinions{
+			&BasicOpinion{Id: "a", DependsOn: []string{"b"}},
+			&BasicOpinion{Id: "b", After: []string{"d"}},
+			&BasicOpinion{Id: "c"},
+			&BasicOpinion{Id: "d"},
+		},
+		expected: []string{"c", "d", "b", "a"},
+	}, {
+		name: "already sorted",
+		opinions: Opinions{
+			&BasicOpinion{Id: "a"},
+			&BasicOpinion{Id: "b", DependsOn: []string{"a"}},
+			&BasicOpinion{Id: "c", After: []string{"a", "b"}},
+		},
+		expected: []string{"a", "b", "c"},
+	}, {
+		name: "cycle",
+		opinions: Opinions{
+			&BasicOpinion{Id: "a", DependsOn: []string{"b"}},
+			&BasicOpinion{Id: "b", After: []string{"a"}},
+			&BasicOpinion{Id: "c"},
+		},
+		expectedReason: "OpinionCycle",
+	}, {
+		name: "self",
+		opinions: Opinions{
+			&BasicOpinion{Id: "a", After: []string{"a"}},
+		},
+		expectedReason: "OpinionCycle",
+	}, {
+		name: "unknown dependency",
+		opinions: Opinions{
+			&BasicOpinion{Id: "a", DependsOn: []string{"missing"}},
+		},
+		expectedReason: "UnknownOpinion",
+	}, {
+		name: "unknown after",
+		opinions: Opinions{
+			&BasicOpinion{Id: "a"},
+			&BasicOpinion{Id: "b", After: []string{"a", "missing"}},
+		},
+		expectedReason: "UnknownOpinion",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sorted, err := test.opinions.Sorted()
+			if test.expectedReason != "" {
+				var opinionErr *Error
+				if !errors.As(err, &opinionErr) || opinionErr.Reason != test.expectedReason {
+					t.Fatalf("expected error with reason %q, got %v", test.expectedReason, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(test.expected, opinionIds(sorted)); diff != "" {
+				t.Errorf("sorted (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestDefault_Sorted(t *testing.T) {
+	sorted, err := Default.Sorted()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(sorted) != len(Default) {
+		t.Errorf("expected %d opinions, got %d", len(Default), len(sorted))
+	}
+	seen := map[string]bool{}
+	for _, o := range Default {
+		if seen[o.GetId()] {
+			t.Errorf("duplicate opinion %q", o.GetId())
+		}
+		seen[o.GetId()] = true
+	}
+}
+
+func TestOpinions_Apply_Prerequisites(t *testing.T) {
+	notApplicable := func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+		return false
+	}
+	os := Opinions{
+		&BasicOpinion{Id: "dependent", DependsOn: []string{"disabled", "base"}},
+		&BasicOpinion{Id: "disabled"},
+		&BasicOpinion{Id: "base"},
+		&BasicOpinion{Id: "inapplicable", ApplicableFunc: notApplicable},
+		&BasicOpinion{Id: "needs-inapplicable", DependsOn: []string{"inapplicable"}},
+		&BasicOpinion{Id: "needs-base", DependsOn: []string{"base"}},
+		&BasicOpinion{Id: "after-disabled", After: []string{"disabled"}},
+	}
+	for _, o := range os {
+		o.(*BasicOpinion).ApplyFunc = func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			return nil
+		}
+	}
+
+	result, err := os.Apply(context.Background(), newTestResource(), 0, cnb.ImageMetadata{}, Options{
+		Disabled: []string{"disabled"},
+		Explain:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(AppliedOpinions{"base", "needs-base", "after-disabled"}, result.Applied); diff != "" {
+		t.Errorf("applied (-expected, +actual) = %v", diff)
+	}
+	if diff := cmp.Diff([]SkippedOpinion{
+		{Id: "disabled", Reason: DisabledReason},
+		{Id: "dependent", Reason: PrerequisiteSkippedReason},
+	}, result.Skipped); diff != "" {
+		t.Errorf("skipped (-expected, +actual) = %v", diff)
+	}
+	reasons := map[string]string{}
+	for _, d := range result.Decisions {
+		reasons[d.Id] = d.Reason
+	}
+	if diff := cmp.Diff(map[string]string{
+		"disabled":           DisabledReason,
+		"base":               "",
+		"dependent":          PrerequisiteSkippedReason,
+		"inapplicable":       NotApplicableReason,
+		"needs-inapplicable": PrerequisiteNotAppliedReason,
+		"needs-base":         "",
+		"after-disabled":     "",
+	}, reasons); diff != "" {
+		t.Errorf("decisions (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestOpinions_Apply_UnknownIds(t *testing.T) {
+	os := Opinions{
+		&BasicOpinion{Id: "a"},
+		&BasicOpinion{Id: "b"},
+	}
+	_, err := os.Apply(context.Background(), newTestResource(), 0, cnb.ImageMetadata{}, Options{Enabled: []string{"a", "c", "d"}, Disabled: []string{"e"}})
+	var opinionErr *Error
+	if !errors.As(err, &opinionErr) || opinionErr.Reason != UnknownOpinionReason {
+		t.Fatalf("expected error with reason %q, got %v", UnknownOpinionReason, err)
+	}
+	if expected := "enabled opinions c, d are unknown"; opinionErr.Message != expected {
+		t.Errorf("expected message %q, got %q", expected, opinionErr.Message)
+	}
+}
+
+func TestOpinions_Apply_UnknownDisabledIds(t *testing.T) {
+	noop := func(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error {
+		return nil
+	}
+	os := Opinions{
+		&BasicOpinion{Id: "a", ApplyFunc: noop},
+		&BasicOpinion{Id: "b", ApplyFunc: noop},
+	}
+	// a disabled opinion may have been deleted since
+	result, err := os.Apply(context.Background(), newTestResource(), 0, cnb.ImageMetadata{}, Options{Disabled: []string{"b", "deleted"}, Explain: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(AppliedOpinions{"a"}, result.Applied); diff != "" {
+		t.Errorf("applied (-expected, +actual) = %v", diff)
+	}
+	if diff := cmp.Diff([]SkippedOpinion{
+		{Id: "deleted", Reason: UnknownOpinionReason, Message: "disabled opinion is unknown"},
+		{Id: "b", Reason: DisabledReason},
+	}, result.Skipped); diff != "" {
+		t.Errorf("skipped (-expected, +actual) = %v", diff)
+	}
+	if diff := cmp.Diff(Decision{Id: "deleted", Reason: UnknownOpinionReason}, result.Decisions[0]); diff != "" {
+		t.Errorf("decision (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestOpinions_Cyclic(t *testing.T) {
+	os := Opinions{
+		&BasicOpinion{Id: "a"},
+		&BasicOpinion{Id: "b", DependsOn: []string{"c"}},
+		&BasicOpinion{Id: "c", After: []string{"d"}},
+		&BasicOpinion{Id: "d", DependsOn: []string{"b", "a"}},
+		// follows the cycle without being part of it
+		&BasicOpinion{Id: "e", DependsOn: []string{"d"}},
+		&BasicOpinion{Id: "f", DependsOn: []string{"f"}},
+		&BasicOpinion{Id: "g", DependsOn: []string{"unknown"}},
+	}
+	if diff := cmp.Diff([]string{"b", "c", "d", "f"}, os.Cyclic()); diff != "" {
+		t.Errorf("cyclic (-expected, +actual) = %v", diff)
+	}
+	if cyclic := Default.Cyclic(); len(cyclic) != 0 {
+		t.Errorf("expected no cycles in the default opinions, got %v", cyclic)
+	}
+}
+
+// libraryMetadata describes an image with the libraries, given as
+// `name@version`
+func libraryMetadata(libraries ...string) cnb.ImageMetadata {
+	imageMetadata := cnb.ImageMetadata{}
+	for _, library := range libraries {
+		parts := strings.SplitN(library, "@", 2)
+		imageMetadata.Dependencies = append(imageMetadata.Dependencies, cnb.Dependency{
+			Name:    parts[0],
+			Version: parts[1],
+			Kind:    cnb.LibraryDependency,
+		})
+	}
+	return imageMetadata
+}
+
+func opinionIds(os Opinions) []string {
+	ids := []string{}
+	for _, o := range os {
+		ids = append(ids, o.GetId())
+	}
+	return ids
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var Quarkus = Opinions{
+	&BasicOpinion{
+		Id:           "quarkus",
+		Dependencies: []string{"quarkus-core"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			return bootMetadata.HasDependency("quarkus-core")
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			SetLabel(target, "apps.mononoke.local/framework", "quarkus")
+			SetAnnotation(target, "quarkus.io/version", bootMetadata.Dependency("quarkus-core").Version)
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id:           "quarkus-http-port",
+		DependsOn:    []string{"quarkus"},
+		Dependencies: []string{"quarkus-vertx-http"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			return bootMetadata.HasDependency("quarkus-vertx-http")
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			applicationProperties := GetSpringApplicationProperties(ctx)
+
+			httpPort := applicationProperties.Default("quarkus.http.port", "8080")
+			port, err := strconv.Atoi(httpPort)
+			if err != nil {
+				return err
+			}
+			if name := addContainerPort(target, containerIdx, int32(port)); name != "" {
+				// port is in use by a different container
+				return fmt.Errorf("desired port %s is in use by container %q, set 'quarkus.http.port' property to an open port", httpPort, name)
+			}
+
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id:        "quarkus-graceful-shutdown",
+		DependsOn: []string{"quarkus-http-port"},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			applicationProperties := GetSpringApplicationProperties(ctx)
+			if _, ok := applicationProperties["quarkus.shutdown.timeout"]; ok {
+				// quarkus shutdown timeout is already defined, skipping
+				return nil
+			}
+			applicationProperties["quarkus.shutdown.timeout"] = fmt.Sprintf("%ds", applicationGracePeriodSeconds(target))
+			return nil
+		},
+	},
+	&BasicOpinion{
+		Id: "quarkus-health-probes",
+		// probes use the port defaulted by the quarkus-http-port opinion
+		DependsOn:    []string{"quarkus-http-port"},
+		Dependencies: []string{"quarkus-smallrye-health"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			return bootMetadata.HasDependency("quarkus-smallrye-health")
+		},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
+			applicationProperties := GetSpringApplicationProperties(ctx)
+
+			port, err := strconv.Atoi(applicationProperties["quarkus.http.port"])
+			if err != nil {
+				return err
+			}
+			// non-application endpoints moved under /q in 1.13
+			basePath := ""
+			if bootMetadata.HasDependencyConstraint("quarkus-core", ">= 1.13.0-0") {
+				basePath = "/q"
+			}
+
+			c := &target.PodTemplate().Spec.Containers[containerIdx]
+			defaultHTTPProbes(c, port, corev1.URISchemeHTTP, basePath+"/health/live", basePath+"/health/ready")
+
+			return nil
+		},
+	},
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func httpGetProbe(path string, port int, initialDelaySeconds int32) *corev1.Probe {
+	return &corev1.Probe{
+		InitialDelaySeconds: initialDelaySeconds,
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromInt(port),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+	}
+}
+
+func TestQuarkus(t *testing.T) {
+	thirtySeconds := int64(30)
+	sixtySeconds := int64(60)
+
+	tests := []struct {
+		name                string
+		imageMetadata       cnb.ImageMetadata
+		properties          SpringApplicationProperties
+		gracePeriodSeconds  *int64
+		expectedApplied     AppliedOpinions
+		expectedProperties  SpringApplicationProperties
+		expectedContainer   corev1.Container
+		expectedGracePeriod *int64
+		expectedAnnotations map[string]string
+	}{{
+		name:                "not quarkus",
+		imageMetadata:       libraryMetadata("spring-boot@2.3.0.RELEASE"),
+		expectedApplied:     AppliedOpinions{},
+		expectedProperties:  SpringApplicationProperties{},
+		expectedContainer:   corev1.Container{Name: "app"},
+		expectedAnnotations: nil,
+	}, {
+		name:                "without http",
+		imageMetadata:       libraryMetadata("quarkus-core@1.13.0.Final"),
+		expectedApplied:     AppliedOpinions{"quarkus"},
+		expectedProperties:  SpringApplicationProperties{},
+		expectedContainer:   corev1.Container{Name: "app"},
+		expectedAnnotations: map[string]string{"quarkus.io/version": "1.13.0.Final"},
+	}, {
+		name:            "http",
+		imageMetadata:   libraryMetadata("quarkus-core@1.13.0.Final", "quarkus-vertx-http@1.13.0.Final"),
+		expectedApplied: AppliedOpinions{"quarkus", "quarkus-http-port", "quarkus-graceful-shutdown"},
+		expectedProperties: SpringApplicationProperties{
+			"quarkus.http.port":        "8080",
+			"quarkus.shutdown.timeout": "24s",
+		},
+		expectedContainer: corev1.Container{
+			Name:  "app",
+			Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+		},
+		expectedGracePeriod: &thirtySeconds,
+		expectedAnnotations: map[string]string{"quarkus.io/version": "1.13.0.Final"},
+	}, {
+		name:               "health",
+		imageMetadata:      libraryMetadata("quarkus-core@1.13.0.Final", "quarkus-vertx-http@1.13.0.Final", "quarkus-smallrye-health@1.13.0.Final"),
+		properties:         SpringApplicationProperties{"quarkus.http.port": "9000", "quarkus.shutdown.timeout": "5s"},
+		gracePeriodSeconds: &sixtySeconds,
+		expectedApplied:    AppliedOpinions{"quarkus", "quarkus-http-port", "quarkus-graceful-shutdown", "quarkus-health-probes"},
+		expectedProperties: SpringApplicationProperties{
+			"quarkus.http.port":        "9000",
+			"quarkus.shutdown.timeout": "5s",
+		},
+		expectedContainer: corev1.Container{
+			Name:           "app",
+			Ports:          []corev1.ContainerPort{{ContainerPort: 9000, Protocol: corev1.ProtocolTCP}},
+			LivenessProbe:  httpGetProbe("/q/health/live", 9000, 30),
+			ReadinessProbe: httpGetProbe("/q/health/ready", 9000, 0),
+		},
+		expectedGracePeriod: &sixtySeconds,
+		expectedAnnotations: map[string]string{"quarkus.io/version": "1.13.0.Final"},
+	}, {
+		name:            "health before 1.13",
+		imageMetadata:   libraryMetadata("quarkus-core@1.12.2.Final", "quarkus-vertx-http@1.12.2.Final", "quarkus-smallrye-health@1.12.2.Final"),
+		expectedApplied: AppliedOpinions{"quarkus", "quarkus-http-port", "quarkus-graceful-shutdown", "quarkus-health-probes"},
+		expectedProperties: SpringApplicationProperties{
+			"quarkus.http.port":        "8080",
+			"quarkus.shutdown.timeout": "24s",
+		},
+		expectedContainer: corev1.Container{
+			Name:           "app",
+			Ports:          []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			LivenessProbe:  httpGetProbe("/health/live", 8080, 30),
+			ReadinessProbe: httpGetProbe("/health/ready", 8080, 0),
+		},
+		expectedGracePeriod: &thirtySeconds,
+		expectedAnnotations: map[string]string{"quarkus.io/version": "1.12.2.Final"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			properties := SpringApplicationProperties{}
+			for key, value := range test.properties {
+				properties[key] = value
+			}
+			ctx := StashSpringApplicationProperties(context.Background(), properties)
+			target := newTestResource()
+			target.Template.Spec.TerminationGracePeriodSeconds = test.gracePeriodSeconds
+
+			result, err := Quarkus.Apply(ctx, target, 0, test.imageMetadata, Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(test.expectedApplied, result.Applied); diff != "" {
+				t.Errorf("applied (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedProperties, properties); diff != "" {
+				t.Errorf("properties (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedContainer, target.Template.Spec.Containers[0]); diff != "" {
+				t.Errorf("container (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedGracePeriod, target.Template.Spec.TerminationGracePeriodSeconds); diff != "" {
+				t.Errorf("grace period (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedAnnotations, target.Template.Annotations); diff != "" {
+				t.Errorf("annotations (-expected, +actual) = %v", diff)
+			}
+			if len(test.expectedApplied) != 0 && target.Template.Labels["apps.mononoke.local/framework"] != "quarkus" {
+				t.Errorf("expected framework label, got %v", target.Template.Labels)
+			}
+		})
+	}
+}
+
+func TestQuarkus_PortInUse(t *testing.T) {
+	target := newTestResource(
+		corev1.Container{Name: "app"},
+		corev1.Container{Name: "sidecar", Ports: []corev1.ContainerPort{{ContainerPort: 8080}}},
+	)
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+	imageMetadata := libraryMetadata("quarkus-core@1.13.0.Final", "quarkus-vertx-http@1.13.0.Final")
+	if _, err := Quarkus.Apply(ctx, target, 0, imageMetadata, Options{}); err == nil {
+		t.Errorf("expected error for a port in use by another container")
+	}
+}
@@ -26,9 +26,9 @@ type Resource interface {
 	PodTemplate() *corev1.PodTemplateSpec
 }
 
-// setAnnotation sets the annotation on both the resource and the resource's
+// SetAnnotation sets the annotation on both the resource and the resource's
 // PodTemplateSpec
-func setAnnotation(r Resource, key, value string) {
+func SetAnnotation(r Resource, key, value string) {
 	parent := r.GetObjectMeta().GetAnnotations()
 	if parent == nil {
 		parent = map[string]string{}
@@ -44,9 +44,9 @@ func setAnnotation(r Resource, key, value string) {
 	template[key] = value
 }
 
-// setLabel sets the label on both the resource and the resource's
+// SetLabel sets the label on both the resource and the resource's
 // PodTemplateSpec
-func setLabel(r Resource, key, value string) {
+func SetLabel(r Resource, key, value string) {
 	parent := r.GetObjectMeta().GetLabels()
 	if parent == nil {
 		parent = map[string]string{}
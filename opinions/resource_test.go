@@ -0,0 +1,57 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testResource is a minimal Resource targeted by the opinions under test
+type testResource struct {
+	metav1.ObjectMeta
+	Template corev1.PodTemplateSpec
+}
+
+func (r *testResource) PodTemplate() *corev1.PodTemplateSpec {
+	return &r.Template
+}
+
+func newTestResource(containers ...corev1.Container) *testResource {
+	if len(containers) == 0 {
+		containers = []corev1.Container{{Name: "app"}}
+	}
+	return &testResource{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: containers},
+		},
+	}
+}
+
+func findOpinion(t *testing.T, os Opinions, id string) Opinion {
+	t.Helper()
+	for _, o := range os {
+		if o.GetId() == id {
+			return o
+		}
+	}
+	t.Fatalf("opinion %q not found", id)
+	return nil
+}
@@ -20,7 +20,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -28,23 +27,23 @@ import (
 	"github.com/Masterminds/semver"
 	"github.com/spring-cloud-incubator/mononoke/cnb"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 var SpringBoot = Opinions{
 	&BasicOpinion{
-		Id: "spring-boot",
-		ApplicableFunc: func(applied AppliedOpinions, imageMetadata cnb.BuildMetadata) bool {
+		Id:           "spring-boot",
+		Dependencies: []string{"spring-boot"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
 			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
 			return bootMetadata.HasDependency("spring-boot")
 		},
-		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.BuildMetadata) error {
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
 			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
-			setLabel(target, "apps.mononoke.local/framework", "spring-boot")
+			SetLabel(target, "apps.mononoke.local/framework", "spring-boot")
 			for _, d := range bootMetadata.Dependencies {
 				if d.Name == "spring-boot" {
-					setAnnotation(target, "boot.spring.io/version", d.Version)
+					SetAnnotation(target, "boot.spring.io/version", d.Version)
 					break
 				}
 			}
@@ -53,7 +52,14 @@ var SpringBoot = Opinions{
 	},
 	&BasicOpinion{
 		Id: "spring-boot-graceful-shutdown",
-		ApplicableFunc: func(applied AppliedOpinions, imageMetadata cnb.BuildMetadata) bool {
+		Dependencies: []string{
+			"spring-boot",
+			"spring-boot-starter-tomcat",
+			"spring-boot-starter-jetty",
+			"spring-boot-starter-reactor-netty",
+			"spring-boot-starter-undertow",
+		},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
 			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
 			return bootMetadata.HasDependencyConstraint("spring-boot", ">= 2.3.0-0") && bootMetadata.HasDependency(
 				"spring-boot-starter-tomcat",
@@ -62,30 +68,24 @@ var SpringBoot = Opinions{
 				"spring-boot-starter-undertow",
 			)
 		},
-		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.BuildMetadata) error {
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
 			applicationProperties := GetSpringApplicationProperties(ctx)
 			if _, ok := applicationProperties["server.shutdown.grace-period"]; ok {
 				// boot grace period is already defined, skipping
 				return nil
 			}
-			var k8sGracePeriodSeconds int64 = 30 // default k8s grace period is 30 seconds
-			if target.PodTemplate().Spec.TerminationGracePeriodSeconds != nil {
-				k8sGracePeriodSeconds = *target.PodTemplate().Spec.TerminationGracePeriodSeconds
-			}
-			target.PodTemplate().Spec.TerminationGracePeriodSeconds = &k8sGracePeriodSeconds
-			// allocate 80% of the k8s grace period to boot
-			bootGracePeriodSeconds := int(math.Floor(0.8 * float64(k8sGracePeriodSeconds)))
-			applicationProperties["server.shutdown.grace-period"] = fmt.Sprintf("%ds", bootGracePeriodSeconds)
+			applicationProperties["server.shutdown.grace-period"] = fmt.Sprintf("%ds", applicationGracePeriodSeconds(target))
 			return nil
 		},
 	},
 	&BasicOpinion{
-		Id: "spring-web-port",
-		ApplicableFunc: func(applied AppliedOpinions, imageMetadata cnb.BuildMetadata) bool {
+		Id:           "spring-web-port",
+		Dependencies: []string{"spring-web"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
 			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
 			return bootMetadata.HasDependency("spring-web")
 		},
-		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.BuildMetadata) error {
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
 			applicationProperties := GetSpringApplicationProperties(ctx)
 
 			serverPort := applicationProperties.Default("server.port", "8080")
@@ -94,14 +94,7 @@ var SpringBoot = Opinions{
 				return err
 			}
 
-			c := &target.PodTemplate().Spec.Containers[containerIdx]
-
-			if name, cp := findContainerPort(target.PodTemplate().Spec, int32(port)); cp == nil {
-				c.Ports = append(c.Ports, corev1.ContainerPort{
-					ContainerPort: int32(port),
-					Protocol:      corev1.ProtocolTCP,
-				})
-			} else if name != c.Name {
+			if name := addContainerPort(target, containerIdx, int32(port)); name != "" {
 				// port is in use by a different container
 				return fmt.Errorf("desired port %s is in use by container %q, set 'server.port' boot property to an open port", serverPort, name)
 			}
@@ -111,32 +104,38 @@ var SpringBoot = Opinions{
 	},
 	&BasicOpinion{
 		Id: "spring-boot-actuator",
-		ApplicableFunc: func(applied AppliedOpinions, imageMetadata cnb.BuildMetadata) bool {
+		// the management port defaults to the server port defaulted by the spring-web-port opinion
+		After:        []string{"spring-web-port"},
+		Dependencies: []string{"spring-boot-actuator"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
 			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
 			return bootMetadata.HasDependency("spring-boot-actuator")
 		},
-		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.BuildMetadata) error {
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
 			applicationProperties := GetSpringApplicationProperties(ctx)
 
-			managementPort := applicationProperties.Default("management.server.port", applicationProperties["server.port"])
+			serverPort := applicationProperties["server.port"]
+			if serverPort == "" {
+				// the spring-web-port opinion was not applied
+				serverPort = "8080"
+			}
+			managementPort := applicationProperties.Default("management.server.port", serverPort)
 			managementBasePath := applicationProperties.Default("management.endpoints.web.base-path", "/actuator")
 			managementScheme := corev1.URISchemeHTTP
 			if applicationProperties["management.server.ssl.enabled"] == "true" {
 				managementScheme = corev1.URISchemeHTTPS
 			}
 
-			setAnnotation(target, "boot.spring.io/actuator", fmt.Sprintf("%s://:%s%s", strings.ToLower(string(managementScheme)), managementPort, managementBasePath))
+			SetAnnotation(target, "boot.spring.io/actuator", fmt.Sprintf("%s://:%s%s", strings.ToLower(string(managementScheme)), managementPort, managementBasePath))
 
 			return nil
 		},
 	},
 	&BasicOpinion{
 		Id: "spring-boot-actuator-probes",
-		ApplicableFunc: func(applied AppliedOpinions, imageMetadata cnb.BuildMetadata) bool {
-			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
-			return bootMetadata.HasDependency("spring-boot-actuator")
-		},
-		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.BuildMetadata) error {
+		// probes use the management port defaulted by the spring-boot-actuator opinion
+		DependsOn: []string{"spring-boot-actuator"},
+		ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
 			bootMetadata := NewSpringBootBOMMetadata(imageMetadata)
 			applicationProperties := GetSpringApplicationProperties(ctx)
 
@@ -171,34 +170,7 @@ var SpringBoot = Opinions{
 				// currently alpha in k8s 1.16+
 				// TODO(scothis) add if k8s can handle it
 			}
-			if c.LivenessProbe == nil {
-				c.LivenessProbe = &corev1.Probe{
-					// increase default to give more time to start
-					// TODO(scothis) remove if a StartupProbe is defined
-					InitialDelaySeconds: 30,
-				}
-			}
-			if c.LivenessProbe.Handler == (corev1.Handler{}) {
-				c.LivenessProbe.Handler = corev1.Handler{
-					HTTPGet: &corev1.HTTPGetAction{
-						Path:   managementBasePath + livenessEndpoint,
-						Port:   intstr.FromInt(managementPort),
-						Scheme: managementScheme,
-					},
-				}
-			}
-			if c.ReadinessProbe == nil {
-				c.ReadinessProbe = &corev1.Probe{}
-			}
-			if c.ReadinessProbe.Handler == (corev1.Handler{}) {
-				c.ReadinessProbe.Handler = corev1.Handler{
-					HTTPGet: &corev1.HTTPGetAction{
-						Path:   managementBasePath + readinessEndpoint,
-						Port:   intstr.FromInt(managementPort),
-						Scheme: managementScheme,
-					},
-				}
-			}
+			defaultHTTPProbes(c, managementPort, managementScheme, managementBasePath+livenessEndpoint, managementBasePath+readinessEndpoint)
 
 			return nil
 		},
@@ -211,6 +183,7 @@ var SpringBoot = Opinions{
 		Dependencies: sets.NewString(
 			"mysql-connector-java",
 			"r2dbc-mysql",
+			"vertx-mysql-client",
 		),
 	},
 	&SpringBootServiceIntent{
@@ -219,6 +192,7 @@ var SpringBoot = Opinions{
 		Dependencies: sets.NewString(
 			"postgresql",
 			"r2dbc-postgresql",
+			"vertx-pg-client",
 		),
 	},
 	&SpringBootServiceIntent{
@@ -240,6 +214,8 @@ var SpringBoot = Opinions{
 		LabelName: "services.mononoke.local/redis",
 		Dependencies: sets.NewString(
 			"jedis",
+			"lettuce-core",
+			"vertx-redis-client",
 		),
 	},
 	&SpringBootServiceIntent{
@@ -260,7 +236,7 @@ var SpringBoot = Opinions{
 	// TODO add a whole lot more opinions
 }
 
-func NewSpringBootBOMMetadata(imageMetadata cnb.BuildMetadata) SpringBootBOMMetadata {
+func NewSpringBootBOMMetadata(imageMetadata cnb.ImageMetadata) SpringBootBOMMetadata {
 	// TODO(scothis) find a better way to convert map[string]interface{} to SpringBootBOMMetadata{}
 	bom := imageMetadata.FindBOM("spring-boot")
 	bootMetadata := SpringBootBOMMetadata{}
@@ -269,6 +245,18 @@ func NewSpringBootBOMMetadata(imageMetadata cnb.BuildMetadata) SpringBootBOMMeta
 		panic(err)
 	}
 	json.Unmarshal(bytes, &bootMetadata)
+	// dependencies are normalized across BOM and SBOM formats
+	bootMetadata.Dependencies = []SpringBootBOMMetadataDependency{}
+	for _, d := range imageMetadata.Dependencies {
+		if d.Kind != cnb.LibraryDependency {
+			continue
+		}
+		bootMetadata.Dependencies = append(bootMetadata.Dependencies, SpringBootBOMMetadataDependency{
+			Name:    d.Name,
+			Sha256:  d.Sha256,
+			Version: d.Version,
+		})
+	}
 	return bootMetadata
 }
 
@@ -357,7 +345,7 @@ func (o *SpringBootServiceIntent) GetId() string {
 	return o.Id
 }
 
-func (o *SpringBootServiceIntent) Applicable(applied AppliedOpinions, metadata cnb.BuildMetadata) bool {
+func (o *SpringBootServiceIntent) Applicable(ctx context.Context, applied AppliedOpinions, metadata cnb.ImageMetadata) bool {
 	bootMetadata := NewSpringBootBOMMetadata(metadata)
 	for _, d := range bootMetadata.Dependencies {
 		if o.Dependencies.Has(d.Name) {
@@ -367,12 +355,22 @@ func (o *SpringBootServiceIntent) Applicable(applied AppliedOpinions, metadata c
 	return false
 }
 
-func (o *SpringBootServiceIntent) Apply(ctx context.Context, target Resource, containerIdx int, metadata cnb.BuildMetadata) error {
+func (o *SpringBootServiceIntent) MatchedDependencies(metadata cnb.ImageMetadata) []cnb.Dependency {
+	matched := []cnb.Dependency{}
+	for _, d := range metadata.Dependencies {
+		if d.Kind == cnb.LibraryDependency && o.Dependencies.Has(d.Name) {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+func (o *SpringBootServiceIntent) Apply(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error {
 	bootMetadata := NewSpringBootBOMMetadata(metadata)
 	for _, d := range bootMetadata.Dependencies {
 		if o.Dependencies.Has(d.Name) {
-			setLabel(target, o.LabelName, target.PodTemplate().Spec.Containers[containerIdx].Name)
-			setAnnotation(target, o.LabelName, fmt.Sprintf("%s/%s", d.Name, d.Version))
+			SetLabel(target, o.LabelName, target.PodTemplate().Spec.Containers[containerIdx].Name)
+			SetAnnotation(target, o.LabelName, fmt.Sprintf("%s/%s", d.Name, d.Version))
 			break
 		}
 	}
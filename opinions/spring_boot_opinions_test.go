@@ -0,0 +1,77 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+)
+
+func TestSpringBootServiceIntent(t *testing.T) {
+	tests := []struct {
+		name                string
+		imageMetadata       cnb.ImageMetadata
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+	}{{
+		name:                "no clients",
+		imageMetadata:       libraryMetadata("micronaut-runtime@2.4.2"),
+		expectedLabels:      nil,
+		expectedAnnotations: nil,
+	}, {
+		name:          "vert.x clients",
+		imageMetadata: libraryMetadata("quarkus-core@1.13.0.Final", "vertx-mysql-client@4.0.3", "vertx-pg-client@4.0.3", "vertx-redis-client@4.0.3"),
+		expectedLabels: map[string]string{
+			"services.mononoke.local/mysql":    "app",
+			"services.mononoke.local/postgres": "app",
+			"services.mononoke.local/redis":    "app",
+		},
+		expectedAnnotations: map[string]string{
+			"services.mononoke.local/mysql":    "vertx-mysql-client/4.0.3",
+			"services.mononoke.local/postgres": "vertx-pg-client/4.0.3",
+			"services.mononoke.local/redis":    "vertx-redis-client/4.0.3",
+		},
+	}, {
+		name:          "lettuce",
+		imageMetadata: libraryMetadata("micronaut-runtime@2.4.2", "lettuce-core@6.1.1.RELEASE"),
+		expectedLabels: map[string]string{
+			"services.mononoke.local/redis": "app",
+		},
+		expectedAnnotations: map[string]string{
+			"services.mononoke.local/redis": "lettuce-core/6.1.1.RELEASE",
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+			target := newTestResource()
+
+			if _, err := SpringBoot.Apply(ctx, target, 0, test.imageMetadata, Options{}); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(test.expectedLabels, target.Template.Labels); diff != "" {
+				t.Errorf("labels (-expected, +actual) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedAnnotations, target.Template.Annotations); diff != "" {
+				t.Errorf("annotations (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FailurePolicy of a webhook opinion, when the webhook can't be called or
+// returns an invalid response
+type FailurePolicy string
+
+const (
+	// FailPolicy fails to apply opinions
+	FailPolicy FailurePolicy = "Fail"
+	// IgnorePolicy skips the opinion
+	IgnorePolicy FailurePolicy = "Ignore"
+)
+
+const (
+	// WebhookFailedReason is the reason applying opinions fails when a
+	// webhook fails
+	WebhookFailedReason = "OpinionWebhookFailed"
+	// WebhookIgnoredReason is the reason a webhook opinion is skipped when
+	// its failure is ignored
+	WebhookIgnoredReason = "OpinionWebhookIgnored"
+)
+
+// maxWebhookResponseSize limits the size of a webhook's response
+const maxWebhookResponseSize = 1024 * 1024
+
+// webhookPatchPaths are the parts of the pod template a webhook may patch,
+// with %d standing for the index of the target container. The image, command
+// and security context of the container are left alone, they were checked by
+// the policies.
+var webhookPatchPaths = []string{
+	"/metadata/labels",
+	"/metadata/annotations",
+	"/spec/containers/%d/env",
+	"/spec/containers/%d/resources",
+}
+
+// WebhookRequest is sent to the webhook of a webhook opinion
+type WebhookRequest struct {
+	// Opinion is the ID of the opinion
+	Opinion       string                      `json:"opinion"`
+	BuildMetadata cnb.BuildMetadata           `json:"buildMetadata"`
+	Properties    SpringApplicationProperties `json:"applicationProperties"`
+	PodTemplate   *corev1.PodTemplateSpec     `json:"podTemplate"`
+	// Container is the name of the target container in the pod template
+	Container string `json:"container"`
+}
+
+// WebhookResponse is returned by the webhook of a webhook opinion
+type WebhookResponse struct {
+	// Patch is the JSON patch (RFC 6902) to apply to the pod template
+	Patch json.RawMessage `json:"patch,omitempty"`
+	// Properties are application properties to default
+	Properties map[string]string `json:"applicationProperties,omitempty"`
+}
+
+// WebhookOpinion is an opinion applied by an HTTP(S) service. The webhook is
+// sent a WebhookRequest, and returns a WebhookResponse with the changes to
+// make. The webhook is called for every image when ApplicableFunc is nil,
+// webhooks that don't apply return no changes.
+type WebhookOpinion struct {
+	Id        string
+	DependsOn []string
+	After     []string
+	// Dependencies of the image the opinion is applicable for, reported when
+	// the opinion is explained
+	Dependencies   []string
+	ApplicableFunc func(ctx context.Context, applied AppliedOpinions, metadata cnb.ImageMetadata) bool
+	URL            string
+	// CABundle is the PEM encoded CA certificates that sign the webhook's
+	// certificate, the system's certificates are used when empty
+	CABundle      []byte
+	Timeout       time.Duration
+	FailurePolicy FailurePolicy
+}
+
+func (o *WebhookOpinion) GetId() string {
+	return o.Id
+}
+
+func (o *WebhookOpinion) GetDependsOn() []string {
+	return o.DependsOn
+}
+
+func (o *WebhookOpinion) GetAfter() []string {
+	return o.After
+}
+
+func (o *WebhookOpinion) MatchedDependencies(metadata cnb.ImageMetadata) []cnb.Dependency {
+	return matchDependencies(metadata, o.Dependencies)
+}
+
+func (o *WebhookOpinion) Applicable(ctx context.Context, applied AppliedOpinions, metadata cnb.ImageMetadata) bool {
+	if o.ApplicableFunc == nil {
+		return true
+	}
+	return o.ApplicableFunc(ctx, applied, metadata)
+}
+
+func (o *WebhookOpinion) Apply(ctx context.Context, target Resource, containerIdx int, metadata cnb.ImageMetadata) error {
+	template := target.PodTemplate()
+	response, err := o.call(ctx, WebhookRequest{
+		Opinion:       o.Id,
+		BuildMetadata: metadata.BuildMetadata,
+		Properties:    GetSpringApplicationProperties(ctx),
+		PodTemplate:   template,
+		Container:     template.Spec.Containers[containerIdx].Name,
+	})
+	if err == nil {
+		err = o.patch(template, containerIdx, response)
+	}
+	if err != nil {
+		message := fmt.Sprintf("opinion %q webhook failed: %s", o.Id, err)
+		if o.FailurePolicy == IgnorePolicy {
+			return &IgnoredError{Reason: WebhookIgnoredReason, Message: message}
+		}
+		return &Error{Reason: WebhookFailedReason, Message: message}
+	}
+	applicationProperties := GetSpringApplicationProperties(ctx)
+	for key, value := range response.Properties {
+		applicationProperties.Default(key, value)
+	}
+	return nil
+}
+
+func (o *WebhookOpinion) call(ctx context.Context, request WebhookRequest) (*WebhookResponse, error) {
+	client, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(res.Body, maxWebhookResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxWebhookResponseSize {
+		return nil, fmt.Errorf("response is larger than %d bytes", maxWebhookResponseSize)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	response := &WebhookResponse{}
+	if err := json.Unmarshal(content, response); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return response, nil
+}
+
+// patch applies the patch of the response to the pod template, leaving the
+// template unchanged when the patch fails to apply or patches a part of the
+// template webhooks may not change
+func (o *WebhookOpinion) patch(template *corev1.PodTemplateSpec, containerIdx int, response *WebhookResponse) error {
+	if len(response.Patch) == 0 || string(response.Patch) == "null" {
+		return nil
+	}
+	patch, err := jsonpatch.DecodePatch(response.Patch)
+	if err != nil {
+		return fmt.Errorf("invalid patch: %w", err)
+	}
+	for _, op := range patch {
+		if op.Kind() == "test" {
+			continue
+		}
+		path, err := op.Path()
+		if err != nil {
+			return fmt.Errorf("invalid patch: %w", err)
+		}
+		paths := []string{path}
+		if op.Kind() == "move" || op.Kind() == "copy" {
+			from, err := op.From()
+			if err != nil {
+				return fmt.Errorf("invalid patch: %w", err)
+			}
+			paths = append(paths, from)
+		}
+		for _, path := range paths {
+			if !webhookPatchAllowed(path, containerIdx) {
+				return fmt.Errorf("patch of %s is not allowed, only labels, annotations, and the env and resources of the target container may be patched", path)
+			}
+		}
+	}
+	original, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	patched, err := patch.Apply(original)
+	if err != nil {
+		return fmt.Errorf("invalid patch: %w", err)
+	}
+	result := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return fmt.Errorf("invalid patch: %w", err)
+	}
+	*template = result
+	return nil
+}
+
+func webhookPatchAllowed(path string, containerIdx int) bool {
+	for _, allowed := range webhookPatchPaths {
+		if strings.Contains(allowed, "%d") {
+			allowed = fmt.Sprintf(allowed, containerIdx)
+		}
+		if path == allowed || strings.HasPrefix(path, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *WebhookOpinion) client() (*http.Client, error) {
+	if len(o.CABundle) == 0 {
+		return http.DefaultClient, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(o.CABundle) {
+		return nil, fmt.Errorf("invalid CA bundle")
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+			// the client is created for each reconcile
+			DisableKeepAlives: true,
+		},
+	}, nil
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opinions
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spring-cloud-incubator/mononoke/cnb"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func webhookServer(t *testing.T, handler func(request WebhookRequest) (int, string)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := WebhookRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid request: %s", err)
+		}
+		status, body := handler(request)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	return server
+}
+
+func webhookTarget() *testResource {
+	return newTestResource(
+		corev1.Container{Name: "sidecar", Image: "sidecar"},
+		corev1.Container{Name: "app", Image: "app"},
+	)
+}
+
+func TestWebhookOpinion_Apply(t *testing.T) {
+	var received WebhookRequest
+	server := webhookServer(t, func(request WebhookRequest) (int, string) {
+		received = request
+		return http.StatusOK, `{
+			"patch": [
+				{"op": "add", "path": "/metadata/labels", "value": {"team": "payments"}},
+				{"op": "add", "path": "/spec/containers/1/env", "value": [{"name": "GREETING", "value": "hello"}]}
+			],
+			"applicationProperties": {"server.port": "9000", "greeting": "hello"}
+		}`
+	})
+	defer server.Close()
+	o := &WebhookOpinion{Id: "provider", URL: server.URL, Timeout: time.Second, FailurePolicy: FailPolicy}
+	imageMetadata := cnb.ImageMetadata{
+		BuildMetadata: cnb.BuildMetadata{Buildpacks: []cnb.Buildpack{{ID: "paketo-buildpacks/java", Version: "5.0.0"}}},
+	}
+	properties := SpringApplicationProperties{"server.port": "8080"}
+	ctx := StashSpringApplicationProperties(context.Background(), properties)
+	target := webhookTarget()
+
+	if !o.Applicable(ctx, AppliedOpinions{}, imageMetadata) {
+		t.Errorf("expected opinion to be applicable without conditions")
+	}
+	if err := o.Apply(ctx, target, 1, imageMetadata); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received.Opinion != "provider" || received.Container != "app" {
+		t.Errorf("unexpected request for opinion %q and container %q", received.Opinion, received.Container)
+	}
+	if diff := cmp.Diff(imageMetadata.BuildMetadata, received.BuildMetadata); diff != "" {
+		t.Errorf("build metadata (-expected, +actual) = %v", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"team": "payments"}, target.Template.Labels); diff != "" {
+		t.Errorf("labels (-expected, +actual) = %v", diff)
+	}
+	if diff := cmp.Diff([]corev1.EnvVar{{Name: "GREETING", Value: "hello"}}, target.Template.Spec.Containers[1].Env); diff != "" {
+		t.Errorf("env (-expected, +actual) = %v", diff)
+	}
+	// properties set by the application take precedence
+	if diff := cmp.Diff(SpringApplicationProperties{"server.port": "8080", "greeting": "hello"}, properties); diff != "" {
+		t.Errorf("properties (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestWebhookOpinion_Apply_Failures(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{{
+		name:   "error status",
+		status: http.StatusInternalServerError,
+		body:   `{}`,
+	}, {
+		name:   "invalid response",
+		status: http.StatusOK,
+		body:   `not json`,
+	}, {
+		name:   "invalid patch",
+		status: http.StatusOK,
+		body:   `{"patch": [{"op": "replace", "path": "/metadata/labels/missing", "value": "x"}]}`,
+	}, {
+		name:   "image patch",
+		status: http.StatusOK,
+		body:   `{"patch": [{"op": "replace", "path": "/spec/containers/1/image", "value": "evil"}]}`,
+	}, {
+		name:   "security context patch",
+		status: http.StatusOK,
+		body:   `{"patch": [{"op": "add", "path": "/spec/containers/1/securityContext", "value": {"privileged": true}}]}`,
+	}, {
+		name:   "other container patch",
+		status: http.StatusOK,
+		body:   `{"patch": [{"op": "add", "path": "/spec/containers/0/env", "value": []}]}`,
+	}, {
+		name:   "template replaced",
+		status: http.StatusOK,
+		body:   `{"patch": [{"op": "replace", "path": "/spec", "value": {"containers": [{"name": "app", "image": "evil"}]}}]}`,
+	}, {
+		name:   "move from image",
+		status: http.StatusOK,
+		body:   `{"patch": [{"op": "move", "from": "/spec/containers/1/image", "path": "/metadata/annotations/image"}]}`,
+	}, {
+		name:   "allowed patch before a disallowed patch",
+		status: http.StatusOK,
+		body:   `{"patch": [{"op": "add", "path": "/metadata/labels", "value": {"team": "payments"}}, {"op": "replace", "path": "/spec/containers/1/command", "value": ["sh"]}]}`,
+	}, {
+		name:   "response too large",
+		status: http.StatusOK,
+		body:   `{"applicationProperties": {"padding": "` + strings.Repeat("x", maxWebhookResponseSize) + `"}}`,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := webhookServer(t, func(request WebhookRequest) (int, string) {
+				return test.status, test.body
+			})
+			defer server.Close()
+			for _, policy := range []FailurePolicy{FailPolicy, IgnorePolicy} {
+				o := &WebhookOpinion{Id: "provider", URL: server.URL, Timeout: time.Second, FailurePolicy: policy}
+				properties := SpringApplicationProperties{}
+				ctx := StashSpringApplicationProperties(context.Background(), properties)
+				target := webhookTarget()
+
+				err := o.Apply(ctx, target, 1, cnb.ImageMetadata{})
+				switch policy {
+				case FailPolicy:
+					var opinionErr *Error
+					if !errors.As(err, &opinionErr) || opinionErr.Reason != WebhookFailedReason {
+						t.Errorf("expected error with reason %q, got %v", WebhookFailedReason, err)
+					}
+				case IgnorePolicy:
+					var ignoredErr *IgnoredError
+					if !errors.As(err, &ignoredErr) || ignoredErr.Reason != WebhookIgnoredReason {
+						t.Errorf("expected ignored error with reason %q, got %v", WebhookIgnoredReason, err)
+					}
+				}
+				if diff := cmp.Diff(webhookTarget(), target); diff != "" {
+					t.Errorf("%s policy changed the target (-expected, +actual) = %v", policy, diff)
+				}
+				if len(properties) != 0 {
+					t.Errorf("%s policy changed properties %v", policy, properties)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookOpinion_Apply_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	o := &WebhookOpinion{Id: "provider", URL: server.URL, Timeout: time.Second, FailurePolicy: FailPolicy}
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+	err := o.Apply(ctx, webhookTarget(), 1, cnb.ImageMetadata{})
+	var opinionErr *Error
+	if !errors.As(err, &opinionErr) || opinionErr.Reason != WebhookFailedReason {
+		t.Errorf("expected error with reason %q, got %v", WebhookFailedReason, err)
+	}
+}
+
+func TestWebhookOpinion_Apply_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"patch": [{"op": "add", "path": "/metadata/annotations", "value": {"tls": "true"}}]}`))
+	}))
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+
+	untrusted := &WebhookOpinion{Id: "provider", URL: server.URL, Timeout: time.Second, FailurePolicy: FailPolicy}
+	if err := untrusted.Apply(ctx, webhookTarget(), 1, cnb.ImageMetadata{}); err == nil {
+		t.Errorf("expected error for a certificate signed by an unknown authority")
+	}
+
+	trusted := &WebhookOpinion{Id: "provider", URL: server.URL, CABundle: caBundle, Timeout: time.Second, FailurePolicy: FailPolicy}
+	target := webhookTarget()
+	if err := trusted.Apply(ctx, target, 1, cnb.ImageMetadata{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(map[string]string{"tls": "true"}, target.Template.Annotations); diff != "" {
+		t.Errorf("annotations (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestWebhookOpinion_Applicable(t *testing.T) {
+	calls := 0
+	server := webhookServer(t, func(request WebhookRequest) (int, string) {
+		calls++
+		return http.StatusOK, `{}`
+	})
+	defer server.Close()
+	o := &WebhookOpinion{
+		Id:           "provider",
+		Dependencies: []string{"spring-web"},
+		ApplicableFunc: func(ctx context.Context, applied AppliedOpinions, imageMetadata cnb.ImageMetadata) bool {
+			return imageMetadata.FindDependency("spring-web", cnb.LibraryDependency) != nil
+		},
+		URL:           server.URL,
+		Timeout:       time.Second,
+		FailurePolicy: FailPolicy,
+	}
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+
+	result, err := Opinions{o}.Apply(ctx, webhookTarget(), 1, cnb.ImageMetadata{}, Options{Explain: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected the webhook not to be called for an image without the dependency, called %d times", calls)
+	}
+	if diff := cmp.Diff([]Decision{{Id: "provider", Reason: NotApplicableReason}}, result.Decisions); diff != "" {
+		t.Errorf("decisions (-expected, +actual) = %v", diff)
+	}
+
+	imageMetadata := cnb.ImageMetadata{
+		Dependencies: []cnb.Dependency{{Name: "spring-web", Version: "5.3.18", Kind: cnb.LibraryDependency}},
+	}
+	result, err = Opinions{o}.Apply(ctx, webhookTarget(), 1, imageMetadata, Options{Explain: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the webhook to be called once, called %d times", calls)
+	}
+	if diff := cmp.Diff([]Decision{{Id: "provider", Applied: true, Dependencies: []string{"spring-web 5.3.18"}}}, result.Decisions); diff != "" {
+		t.Errorf("decisions (-expected, +actual) = %v", diff)
+	}
+}
+
+func TestWebhookOpinion_Ignored(t *testing.T) {
+	server := webhookServer(t, func(request WebhookRequest) (int, string) {
+		return http.StatusServiceUnavailable, ``
+	})
+	defer server.Close()
+	os := Opinions{
+		&WebhookOpinion{Id: "provider", URL: server.URL, Timeout: time.Second, FailurePolicy: IgnorePolicy},
+		&BasicOpinion{
+			Id:        "dependent",
+			DependsOn: []string{"provider"},
+			ApplyFunc: func(ctx context.Context, target Resource, containerIdx int, imageMetadata cnb.ImageMetadata) error {
+				return nil
+			},
+		},
+	}
+	ctx := StashSpringApplicationProperties(context.Background(), SpringApplicationProperties{})
+
+	result, err := os.Apply(ctx, webhookTarget(), 1, cnb.ImageMetadata{}, Options{Explain: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(result.Applied) != 0 {
+		t.Errorf("expected no applied opinions, got %v", result.Applied)
+	}
+	reasons := []string{}
+	for _, s := range result.Skipped {
+		reasons = append(reasons, s.Id+": "+s.Reason)
+	}
+	if diff := cmp.Diff([]string{"provider: " + WebhookIgnoredReason, "dependent: " + PrerequisiteSkippedReason}, reasons); diff != "" {
+		t.Errorf("skipped (-expected, +actual) = %v", diff)
+	}
+	if !strings.Contains(result.Skipped[0].Message, "503") {
+		t.Errorf("expected the failure in the message, got %q", result.Skipped[0].Message)
+	}
+	if diff := cmp.Diff([]Decision{
+		{Id: "provider", Reason: WebhookIgnoredReason},
+		{Id: "dependent", Reason: PrerequisiteSkippedReason},
+	}, result.Decisions); diff != "" {
+		t.Errorf("decisions (-expected, +actual) = %v", diff)
+	}
+}